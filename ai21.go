@@ -0,0 +1,205 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// AI21Jurassic2Request is a request body for AI21 Labs Jurassic-2 models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-jurassic2.html
+type AI21Jurassic2Request struct {
+	Prompt           string              `json:"prompt"`
+	MaxTokens        int                 `json:"maxTokens,omitempty"`
+	Temperature      float64             `json:"temperature,omitempty"`
+	TopP             float64             `json:"topP,omitempty"`
+	StopSequences    []string            `json:"stopSequences,omitempty"`
+	NumResults       int                 `json:"numResults,omitempty"`
+	CountPenalty     *AI21PenaltyOptions `json:"countPenalty,omitempty"`
+	PresencePenalty  *AI21PenaltyOptions `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *AI21PenaltyOptions `json:"frequencyPenalty,omitempty"`
+}
+
+type AI21PenaltyOptions struct {
+	Scale float64 `json:"scale"`
+}
+
+type AI21Jurassic2Response struct {
+	ID          any                       `json:"id"`
+	Completions []AI21Jurassic2Completion `json:"completions"`
+}
+
+type AI21Jurassic2Completion struct {
+	Data struct {
+		Text string `json:"text"`
+	} `json:"data"`
+	FinishReason struct {
+		Reason string `json:"reason"`
+	} `json:"finishReason"`
+}
+
+// AI21JambaRequest is a request body for AI21 Labs Jamba models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-jamba.html
+type AI21JambaRequest struct {
+	Messages         []AI21JambaMessage `json:"messages"`
+	MaxTokens        int                `json:"max_tokens,omitempty"`
+	Temperature      float64            `json:"temperature,omitempty"`
+	TopP             float64            `json:"top_p,omitempty"`
+	Stop             []string           `json:"stop,omitempty"`
+	N                int                `json:"n,omitempty"`
+	FrequencyPenalty float64            `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64            `json:"presence_penalty,omitempty"`
+}
+
+type AI21JambaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type AI21JambaResponse struct {
+	ID      string            `json:"id"`
+	Choices []AI21JambaChoice `json:"choices"`
+	Usage   struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+type AI21JambaChoice struct {
+	Index        int              `json:"index"`
+	Message      AI21JambaMessage `json:"message"`
+	FinishReason string           `json:"finish_reason"`
+}
+
+func newAI21PenaltyOptions(scale float64) *AI21PenaltyOptions {
+	if scale == 0 {
+		return nil
+	}
+	return &AI21PenaltyOptions{Scale: scale}
+}
+
+func (l *LLM) generateContentWithAI21Jurassic2(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	prompt, err := renderChatTranscript(messages, "User:", "Assistant:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	payload := AI21Jurassic2Request{
+		Prompt:           prompt,
+		MaxTokens:        opts.MaxTokens,
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
+		StopSequences:    opts.StopWords,
+		NumResults:       opts.CandidateCount,
+		CountPenalty:     newAI21PenaltyOptions(opts.RepetitionPenalty),
+		PresencePenalty:  newAI21PenaltyOptions(opts.PresencePenalty),
+		FrequencyPenalty: newAI21PenaltyOptions(opts.FrequencyPenalty),
+	}
+	l.logger.Debug("generate content with ai21 jurassic-2", "payload", payload)
+	var resp AI21Jurassic2Response
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Completions) == 0 {
+		return nil, errors.New("no completions in response")
+	}
+	choices := make([]*llms.ContentChoice, 0, len(resp.Completions))
+	for _, c := range resp.Completions {
+		choices = append(choices, &llms.ContentChoice{
+			Content:    c.Data.Text,
+			StopReason: c.FinishReason.Reason,
+			GenerationInfo: map[string]interface{}{
+				"model": opts.Model,
+			},
+		})
+	}
+	return &llms.ContentResponse{
+		Choices: choices,
+	}, nil
+}
+
+func convertRoleForAI21Jamba(role llms.ChatMessageType) (string, error) {
+	switch role {
+	case llms.ChatMessageTypeSystem:
+		return "system", nil
+	case llms.ChatMessageTypeHuman:
+		return "user", nil
+	case llms.ChatMessageTypeAI:
+		return "assistant", nil
+	default:
+		return "", fmt.Errorf("unsupported role: %s", role)
+	}
+}
+
+func convertMessagesForAI21Jamba(messages []llms.MessageContent) ([]AI21JambaMessage, error) {
+	converted := make([]AI21JambaMessage, 0, len(messages))
+	for _, message := range messages {
+		role, err := convertRoleForAI21Jamba(message.Role)
+		if err != nil {
+			return nil, err
+		}
+		text, err := joinTextParts(message)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, AI21JambaMessage{
+			Role:    role,
+			Content: text,
+		})
+	}
+	return converted, nil
+}
+
+func (l *LLM) generateContentWithAI21Jamba(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	jambaMessages, err := convertMessagesForAI21Jamba(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+	payload := AI21JambaRequest{
+		Messages:         jambaMessages,
+		MaxTokens:        opts.MaxTokens,
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
+		Stop:             opts.StopWords,
+		N:                opts.CandidateCount,
+		FrequencyPenalty: opts.FrequencyPenalty,
+		PresencePenalty:  opts.PresencePenalty,
+	}
+	l.logger.Debug("generate content with ai21 jamba", "payload", payload)
+	var resp AI21JambaResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no choices in response")
+	}
+	choices := make([]*llms.ContentChoice, 0, len(resp.Choices))
+	for i, c := range resp.Choices {
+		info := map[string]interface{}{
+			"model": opts.Model,
+		}
+		// Jamba reports the usage of all the choices together, so it is set on the first choice only.
+		if i == 0 {
+			info["usage.input_tokens"] = resp.Usage.PromptTokens
+			info["usage.output_tokens"] = resp.Usage.CompletionTokens
+		}
+		choices = append(choices, &llms.ContentChoice{
+			Content:        c.Message.Content,
+			StopReason:     c.FinishReason,
+			GenerationInfo: info,
+		})
+	}
+	return &llms.ContentResponse{
+		Choices: choices,
+	}, nil
+}
@@ -2,26 +2,40 @@ package bedrock
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/llms"
 )
 
 type LLM struct {
-	CallbacksHandler callbacks.Handler
-	client           BedrockClient
-	logger           *slog.Logger
-	numWorkers       int
-	model            string
-	embeddingModel   string
-	maxTokens        int
-	topK             int
-	topP             float64
-	temperature      float64
-	stopWords        []string
+	CallbacksHandler    callbacks.Handler
+	client              BedrockClient
+	logger              *slog.Logger
+	numWorkers          int
+	model               string
+	modelAliases        map[string]string
+	fallbackModels      []string
+	imageModel          string
+	embeddingModel      string
+	embeddingDimensions int
+	embeddingNormalize  *bool
+	embeddingInputType  string
+	maxTokens           int
+	topK                int
+	topP                float64
+	temperature         float64
+	stopWords           []string
+	frequencyPenalty    float64
+	presencePenalty     float64
+	useConverseAPI      bool
+	usageTracker        *UsageTracker
 }
 
 var _ llms.Model = (*LLM)(nil)
@@ -31,31 +45,105 @@ var _ embeddings.EmbedderClient = (*LLM)(nil)
 func New(opts ...Option) (*LLM, error) {
 	o := newOptions()
 	o.apply(opts...)
-	client, err := o.newBedrockClient(context.Background())
+	regionClients, err := o.newRegionClients(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	return &LLM{
-		client:         client,
-		logger:         o.logger,
-		numWorkers:     o.numWorkers,
-		model:          o.model,
-		embeddingModel: o.embeddingModel,
-		maxTokens:      o.maxTokens,
-		topK:           o.topK,
-		topP:           o.topP,
-		temperature:    o.temperature,
-		stopWords:      o.stopWords,
-	}, nil
+	if len(regionClients) == 0 {
+		client, err := o.newBedrockClient(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		regionClients = []regionClient{{region: o.region, client: client}}
+	}
+	l := &LLM{
+		logger:              o.logger,
+		numWorkers:          o.numWorkers,
+		model:               o.model,
+		modelAliases:        o.modelAliases,
+		fallbackModels:      o.fallbackModels,
+		imageModel:          o.imageModel,
+		embeddingModel:      o.embeddingModel,
+		embeddingDimensions: o.embeddingDimensions,
+		embeddingNormalize:  o.embeddingNormalize,
+		embeddingInputType:  o.embeddingInputType,
+		maxTokens:           o.maxTokens,
+		topK:                o.topK,
+		topP:                o.topP,
+		temperature:         o.temperature,
+		stopWords:           o.stopWords,
+		frequencyPenalty:    o.frequencyPenalty,
+		presencePenalty:     o.presencePenalty,
+		useConverseAPI:      o.useConverseAPI,
+		usageTracker:        o.usageTracker,
+	}
+	// wrap the client from inner to outer: rate limit and circuit breaker per region, failover, then retry,
+	// so each attempt is limited and counted in the region that serves it.
+	for i, rc := range regionClients {
+		if len(o.rateLimits) > 0 {
+			rc.client = newRateLimitClient(rc.client, o.rateLimits, o.logger)
+		}
+		if o.circuitBreaker != nil {
+			rc.client = newCircuitBreakerClient(rc.client, *o.circuitBreaker, l.handleCircuitBreakerStateChange)
+		}
+		regionClients[i] = rc
+	}
+	client := regionClients[0].client
+	if len(o.regions) > 0 || len(o.regionClients) > 0 {
+		client = newFailoverClient(regionClients, o.logger)
+	}
+	if o.retryPolicy != nil {
+		client = newRetryClient(client, *o.retryPolicy, o.logger)
+	}
+	l.client = client
+	return l, nil
+}
+
+// EmbeddingResponse is the result of CreateEmbeddingWithUsage.
+type EmbeddingResponse struct {
+	Embeddings [][]float32
+	// InputTokenCounts is the number of input tokens consumed for each text.
+	// It is nil if the model does not report token counts.
+	InputTokenCounts []int
+}
+
+// TotalInputTokenCount returns the sum of InputTokenCounts.
+func (r *EmbeddingResponse) TotalInputTokenCount() int {
+	var total int
+	for _, c := range r.InputTokenCounts {
+		total += c
+	}
+	return total
 }
 
 func (l *LLM) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
 	l.logger.Debug("bedrock.LLM.CreateEmbedding called", "texts", texts)
-	switch l.embeddingModel {
-	case TitanEmbeddingG1Text:
+	resp, err := l.CreateEmbeddingWithUsage(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Embeddings, nil
+}
+
+// CreateEmbeddingWithUsage is like CreateEmbedding, but also returns the input token counts reported by the model.
+func (l *LLM) CreateEmbeddingWithUsage(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	ctx, usage := contextWithInvocationUsage(ctx)
+	resp, err := l.createEmbeddingWithModel(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	l.recordEmbeddingUsage(ctx, resp, usage)
+	return resp, nil
+}
+
+func (l *LLM) createEmbeddingWithModel(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	switch l.lookupModelFamily(l.embeddingModel) {
+	case ModelFamilyTitanEmbedding, ModelFamilyTitanEmbeddingV2, ModelFamilyTitanMultimodalEmbedding:
 		return l.createEmbeddingWithTaitan(ctx, texts)
+	case ModelFamilyCohereEmbed:
+		return l.createEmbeddingWithCohere(ctx, texts)
 	default:
-		return nil, fmt.Errorf("embedding model `%s` not supported", l.embeddingModel)
+		return nil, newUnsupportedModelError("embedding", l.embeddingModel)
 	}
 }
 
@@ -71,29 +159,183 @@ func (l *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 	for _, opt := range options {
 		opt(opts)
 	}
-	var resp *llms.ContentResponse
-	var err error
-	switch l.model {
-	case Claude2, ClaudeInstant:
-		resp, err = l.generateContentWithClaude2(ctx, messages, opts)
-	case Claude3Sonnet, Claude3Haiku:
-		resp, err = l.generateContentWithClaude3(ctx, messages, opts)
-	default:
-		err = fmt.Errorf("model `%s` not supported", l.model)
+	if opts.Model == "" {
+		opts.Model = l.model
 	}
+	ctx, region := contextWithServedRegion(ctx)
+	ctx, usage := contextWithInvocationUsage(ctx)
+	resp, err := l.generateContentWithFallback(ctx, messages, opts)
 	if err != nil {
 		if l.CallbacksHandler != nil {
 			l.CallbacksHandler.HandleLLMError(ctx, err)
 		}
 		return nil, err
 	}
+	l.recordContentResponseUsage(ctx, resp, usage)
+	if r := region.get(); r != "" {
+		for _, choice := range resp.Choices {
+			if choice.GenerationInfo == nil {
+				choice.GenerationInfo = make(map[string]interface{})
+			}
+			choice.GenerationInfo["region"] = r
+		}
+	}
 	if l.CallbacksHandler != nil {
 		l.CallbacksHandler.HandleLLMGenerateContentEnd(ctx, resp)
 	}
 	return resp, nil
 }
 
+// generateContentWithModel generates content with the request codec of the opts.Model family in the model registry.
+func (l *LLM) generateContentWithModel(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	info, ok := l.lookupModel(opts.Model)
+	if !ok || !info.Family.IsTextGeneration() {
+		return nil, newUnsupportedModelError("", opts.Model)
+	}
+	if err := validateModelCapabilities(info, messages, opts); err != nil {
+		return nil, err
+	}
+	switch info.Family {
+	case ModelFamilyClaude2:
+		return l.generateContentWithClaude2(ctx, messages, opts)
+	case ModelFamilyClaude3:
+		return l.generateContentWithClaude3(ctx, messages, opts)
+	case ModelFamilyLlama2:
+		return l.generateContentWithLlama(ctx, messages, opts, renderLlama2Prompt)
+	case ModelFamilyLlama3:
+		return l.generateContentWithLlama(ctx, messages, opts, renderLlama3Prompt)
+	case ModelFamilyMistral:
+		return l.generateContentWithMistral(ctx, messages, opts)
+	case ModelFamilyTitanText:
+		return l.generateContentWithTitanText(ctx, messages, opts)
+	case ModelFamilyCohereCommand:
+		return l.generateContentWithCohereCommand(ctx, messages, opts)
+	case ModelFamilyCohereCommandR:
+		return l.generateContentWithCohereCommandR(ctx, messages, opts)
+	case ModelFamilyAI21Jurassic2:
+		return l.generateContentWithAI21Jurassic2(ctx, messages, opts)
+	case ModelFamilyAI21Jamba:
+		return l.generateContentWithAI21Jamba(ctx, messages, opts)
+	default:
+		return nil, newUnsupportedModelError("", opts.Model)
+	}
+}
+
 func (l *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
 	l.logger.Debug("bedrock.LLM.Call called", "prompt", prompt)
 	return llms.GenerateFromSinglePrompt(ctx, l, prompt, options...)
 }
+
+// applyDefaultCallOptions fills unset call options with the LLM defaults.
+func (l *LLM) applyDefaultCallOptions(opts *llms.CallOptions) {
+	if opts.MaxTokens == 0 {
+		opts.MaxTokens = l.maxTokens
+	}
+	if opts.Temperature == 0 {
+		opts.Temperature = l.temperature
+	}
+	if opts.TopP == 0 {
+		opts.TopP = l.topP
+	}
+	if opts.TopK == 0 {
+		opts.TopK = l.topK
+	}
+	if opts.StopWords == nil {
+		opts.StopWords = l.defaultStopWords(opts.Model)
+	}
+	if opts.FrequencyPenalty == 0 {
+		opts.FrequencyPenalty = l.frequencyPenalty
+	}
+	if opts.PresencePenalty == 0 {
+		opts.PresencePenalty = l.presencePenalty
+	}
+}
+
+// defaultStopWords returns the stop words of the LLM for the model.
+// Without WithStopWords, `Human:` stops text completion models from continuing the conversation.
+// Titan Text only accepts `|` and `User:` as stop sequences,
+// so the stop word for Claude `Human:` is replaced with `User:`.
+func (l *LLM) defaultStopWords(modelID string) []string {
+	defaults := l.stopWords
+	if defaults == nil {
+		defaults = []string{"Human:"}
+	}
+	if l.lookupModelFamily(modelID) != ModelFamilyTitanText {
+		return defaults
+	}
+	stopWords := make([]string, 0, len(defaults))
+	for _, word := range defaults {
+		if word == "Human:" {
+			word = "User:"
+		}
+		stopWords = append(stopWords, word)
+	}
+	return stopWords
+}
+
+// invokeModelWithJSON invokes modelID with payload encoded as JSON and decodes the response body into resp.
+func (l *LLM) invokeModelWithJSON(ctx context.Context, modelID string, payload any, resp any) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return l.invokeModel(ctx, modelID, payloadBytes, resp)
+}
+
+// invokeModel invokes modelID with the JSON body and decodes the response body into resp.
+func (l *LLM) invokeModel(ctx context.Context, modelID string, body []byte, resp any) error {
+	output, err := l.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return wrapAPIError("failed to invoke model", err)
+	}
+	if inputTokens, outputTokens, ok := tokenCountsFromMetadata(output.ResultMetadata); ok {
+		addInvocationUsage(ctx, inputTokens, outputTokens)
+	}
+	if err := json.Unmarshal(output.Body, resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// joinTextParts joins the text parts of the message. it returns an error if the message has non-text parts.
+func joinTextParts(message llms.MessageContent) (string, error) {
+	texts := make([]string, 0, len(message.Parts))
+	for _, part := range message.Parts {
+		textPart, ok := part.(llms.TextContent)
+		if !ok {
+			return "", newUnsupportedContentError(part)
+		}
+		texts = append(texts, textPart.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// renderChatTranscript renders messages into a plain text chat transcript for text completion models.
+//
+//	{system}\n\n{humanLabel} {human}\n{aiLabel} {ai}\n{humanLabel} {human}\n{aiLabel}
+func renderChatTranscript(messages []llms.MessageContent, humanLabel, aiLabel string) (string, error) {
+	var builder strings.Builder
+	for _, message := range messages {
+		text, err := joinTextParts(message)
+		if err != nil {
+			return "", err
+		}
+		text = strings.TrimSpace(text)
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			builder.WriteString(text + "\n\n")
+		case llms.ChatMessageTypeHuman:
+			builder.WriteString(humanLabel + " " + text + "\n")
+		case llms.ChatMessageTypeAI:
+			builder.WriteString(aiLabel + " " + text + "\n")
+		default:
+			return "", fmt.Errorf("unsupported role: %s", message.Role)
+		}
+	}
+	builder.WriteString(aiLabel)
+	return builder.String(), nil
+}
@@ -1,23 +1,35 @@
 package bedrock_test
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	bedrock "github.com/mashiike/langchaingo-llm-bedrock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/schema"
 )
 
 type mockBedrockClient struct {
@@ -31,23 +43,109 @@ func newMockBedrockClient(t *testing.T) *mockBedrockClient {
 	return c
 }
 
-func (m *mockBedrockClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+// mockCall records the call of the method and returns the output of the expectation as Out.
+func mockCall[Out any](m *mockBedrockClient, method string, ctx context.Context, params any, optFns []func(*bedrockruntime.Options)) (Out, error) {
 	var args mock.Arguments
 	if len(optFns) > 0 {
-		args = m.Called(ctx, params, optFns)
+		args = m.MethodCalled(method, ctx, params, optFns)
 	} else {
-		args = m.Called(ctx, params)
+		args = m.MethodCalled(method, ctx, params)
 	}
+	var zero Out
 	output := args.Get(0)
 	err := args.Error(1)
 	if output == nil {
-		return nil, err
+		return zero, err
 	}
-	if o, ok := output.(*bedrockruntime.InvokeModelOutput); ok {
+	if o, ok := output.(Out); ok {
 		return o, err
 	}
 	m.t.Errorf("unexpected output type: %T", output)
-	return nil, err
+	return zero, err
+}
+
+func (m *mockBedrockClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	return mockCall[*bedrockruntime.InvokeModelOutput](m, "InvokeModel", ctx, params, optFns)
+}
+
+func (m *mockBedrockClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return mockCall[*bedrockruntime.InvokeModelWithResponseStreamOutput](m, "InvokeModelWithResponseStream", ctx, params, optFns)
+}
+
+func (m *mockBedrockClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	return mockCall[*bedrockruntime.ConverseOutput](m, "Converse", ctx, params, optFns)
+}
+
+func (m *mockBedrockClient) ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error) {
+	return mockCall[*bedrockruntime.ConverseStreamOutput](m, "ConverseStream", ctx, params, optFns)
+}
+
+type streamEvent struct {
+	eventType string
+	payload   string
+}
+
+// responseStreamHTTPClient is a fake HTTP client for bedrockruntime.Client, that returns the given events as event stream.
+// Stream outputs can not be constructed outside of the SDK, so streaming tests use real client with this.
+type responseStreamHTTPClient struct {
+	t      *testing.T
+	events []streamEvent
+	bodies [][]byte
+}
+
+func newStreamClient(t *testing.T, events ...streamEvent) (*bedrockruntime.Client, *responseStreamHTTPClient) {
+	t.Helper()
+	httpClient := &responseStreamHTTPClient{t: t, events: events}
+	client := bedrockruntime.New(bedrockruntime.Options{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  httpClient,
+	})
+	return client, httpClient
+}
+
+// newResponseStreamClient returns a client, that returns the given chunks as InvokeModelWithResponseStream events.
+func newResponseStreamClient(t *testing.T, chunks ...string) (*bedrockruntime.Client, *responseStreamHTTPClient) {
+	t.Helper()
+	events := make([]streamEvent, 0, len(chunks))
+	for _, chunk := range chunks {
+		payload, err := json.Marshal(map[string]string{
+			"bytes": base64.StdEncoding.EncodeToString([]byte(chunk)),
+		})
+		require.NoError(t, err)
+		events = append(events, streamEvent{eventType: "chunk", payload: string(payload)})
+	}
+	return newStreamClient(t, events...)
+}
+
+func (c *responseStreamHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		bs, err := io.ReadAll(req.Body)
+		require.NoError(c.t, err)
+		c.bodies = append(c.bodies, bs)
+	}
+	var buf bytes.Buffer
+	encoder := eventstream.NewEncoder()
+	for _, event := range c.events {
+		err := encoder.Encode(&buf, eventstream.Message{
+			Headers: eventstream.Headers{
+				{Name: ":message-type", Value: eventstream.StringValue("event")},
+				{Name: ":event-type", Value: eventstream.StringValue(event.eventType)},
+				{Name: ":content-type", Value: eventstream.StringValue("application/json")},
+			},
+			Payload: []byte(event.payload),
+		})
+		require.NoError(c.t, err)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":                []string{"application/vnd.amazon.eventstream"},
+			"X-Amzn-Bedrock-Content-Type": []string{"application/json"},
+		},
+		Body:    io.NopCloser(&buf),
+		Request: req,
+	}, nil
 }
 
 var flagUseRemote = flag.Bool("use-remote", false, "run tests with remote resources")
@@ -129,7 +227,7 @@ func TestGenerateContentWithClaude2(t *testing.T) {
 	)
 	require.NoError(t, err)
 	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
-		llms.TextParts(schema.ChatMessageTypeHuman,
+		llms.TextParts(llms.ChatMessageTypeHuman,
 			`答えを知っているか、あるいは十分な推測ができる場合のみ、以下の質問に答えてください。答えられない場合は『わかりません』を出力してください。
 これまで記録された中で最も重いカバは？」`),
 	})
@@ -138,6 +236,84 @@ func TestGenerateContentWithClaude2(t *testing.T) {
 	require.Contains(t, resp.Choices[0].Content, "わかりません")
 }
 
+func TestMockGenerateContentWithClaude2Stream(t *testing.T) {
+	client, httpClient := newResponseStreamClient(t,
+		`{"completion":" わかり","stop_reason":null,"stop":null}`,
+		`{"completion":"ません","stop_reason":null,"stop":null}`,
+		`{"completion":"","stop_reason":"stop_sequence","stop":"\n\nHuman:","amazon-bedrock-invocationMetrics":{"inputTokenCount":20,"outputTokenCount":5,"invocationLatency":300,"firstByteLatency":200}}`,
+	)
+	llm, err := bedrock.New(
+		bedrock.WithClient(client),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.ClaudeInstant),
+	)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		var chunks []string
+		resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, "これまで記録された中で最も重いカバは？"),
+		}, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		}))
+		require.NoError(t, err)
+		require.Contains(t, string(httpClient.bodies[len(httpClient.bodies)-1]), `"prompt":"\n\nHuman:これまで記録された中で最も重いカバは？\n\nAssistant:"`)
+		require.EqualValues(t, []string{" わかり", "ません"}, chunks)
+		require.Equal(t, " わかりません", resp.Choices[0].Content)
+		require.Equal(t, "stop_sequence", resp.Choices[0].StopReason)
+	})
+	t.Run("abort", func(t *testing.T) {
+		errAbort := errors.New("abort")
+		var chunks []string
+		_, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, "これまで記録された中で最も重いカバは？"),
+		}, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return errAbort
+		}))
+		require.ErrorIs(t, err, errAbort)
+		require.EqualValues(t, []string{" わかり"}, chunks)
+	})
+}
+
+func TestMockGenerateContentWithClaude2MultiTurn(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.ClaudeInstant {
+				return false
+			}
+			return assert.JSONEq(t, `{
+	"prompt":"You are a helpful assistant.\n\nHuman: Hello\n\nAssistant: Hi! How can I help you?\n\nHuman: What is the heaviest hippo ever recorded?\n\nAssistant:",
+	"max_tokens_to_sample":1000,
+	"temperature":0.7,
+	"top_p":0.9,
+	"top_k":50,
+	"stop_sequences":["Human:"]
+}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" It weighed 4,500 kg.","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.ClaudeInstant),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, " It weighed 4,500 kg.", resp.Choices[0].Content)
+}
+
 func TestGenerateContentWithClaude3Haiku(t *testing.T) {
 	if !*flagUseRemote {
 		t.Skip("skipping test; use -use-remote to enable")
@@ -149,7 +325,7 @@ func TestGenerateContentWithClaude3Haiku(t *testing.T) {
 	)
 	require.NoError(t, err)
 	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
-		llms.TextParts(schema.ChatMessageTypeHuman,
+		llms.TextParts(llms.ChatMessageTypeHuman,
 			`答えを知っているか、あるいは十分な推測ができる場合のみ、以下の質問に答えてください。答えられない場合は『わかりません』を出力してください。
 これまで記録された中で最も重いカバは？」`),
 	})
@@ -173,7 +349,7 @@ func TestGenerateContentWithClaude3HaikuWithImage(t *testing.T) {
 	require.NoError(t, err)
 	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
 		{
-			Role: schema.ChatMessageTypeHuman,
+			Role: llms.ChatMessageTypeHuman,
 			Parts: []llms.ContentPart{
 				llms.BinaryPart("image/png", image),
 				llms.TextPart("この画像に書かれてる単語は何？"),
@@ -250,7 +426,7 @@ func TestMockGenerateContentWithClaude3HaikuWithImage(t *testing.T) {
 	require.NoError(t, err)
 	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
 		{
-			Role: schema.ChatMessageTypeHuman,
+			Role: llms.ChatMessageTypeHuman,
 			Parts: []llms.ContentPart{
 				llms.BinaryPart("image/png", image),
 				llms.TextPart("この画像に書かれてる単語は何？"),
@@ -261,3 +437,2043 @@ func TestMockGenerateContentWithClaude3HaikuWithImage(t *testing.T) {
 	t.Log(resp.Choices[0].Content)
 	require.Contains(t, resp.Choices[0].Content, "LGTM")
 }
+
+func TestMockGenerateContentWithClaude3HaikuStream(t *testing.T) {
+	client, httpClient := newResponseStreamClient(t,
+		`{"type":"message_start","message":{"id":"msg_000000000000000000000000","type":"message","role":"assistant","content":[],"model":"claude-3-haiku-48k-20240307","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"わかり"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ません"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":8}}`,
+		`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":25,"outputTokenCount":8,"invocationLatency":300,"firstByteLatency":200}}`,
+	)
+	llm, err := bedrock.New(
+		bedrock.WithClient(client),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+	)
+	require.NoError(t, err)
+	var chunks []string
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "これまで記録された中で最も重いカバは？"),
+	}, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	}))
+	require.NoError(t, err)
+	require.Len(t, httpClient.bodies, 1)
+	require.Contains(t, string(httpClient.bodies[0]), `"anthropic_version":"bedrock-2023-05-31"`)
+	require.EqualValues(t, []string{"わかり", "ません"}, chunks)
+	require.Len(t, resp.Choices, 1)
+	require.Equal(t, "わかりません", resp.Choices[0].Content)
+	require.Equal(t, "end_turn", resp.Choices[0].StopReason)
+	require.Equal(t, 25, resp.Choices[0].GenerationInfo["usage.input_tokens"])
+	require.Equal(t, 8, resp.Choices[0].GenerationInfo["usage.output_tokens"])
+}
+
+func TestMockGenerateContentWithClaude3HaikuToolUse(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if input.ModelId == nil {
+				return false
+			}
+			if *input.ModelId != bedrock.Claude3Haiku {
+				return false
+			}
+			return assert.JSONEq(t, `{
+	"temperature":0.7,
+	"top_p":0.9,
+	"top_k":50,
+	"stop_sequences":["Human:"],
+	"max_tokens":1000,
+	"messages":[
+		{
+			"role":"user",
+			"content":[{"type":"text","text":"東京の天気は？"}]
+		},
+		{
+			"role":"assistant",
+			"content":[
+				{"type":"tool_use","id":"toolu_01","name":"get_weather","input":{"location":"Tokyo"}}
+			]
+		},
+		{
+			"role":"user",
+			"content":[
+				{"type":"tool_result","tool_use_id":"toolu_01","content":"晴れ"}
+			]
+		}
+	],
+	"anthropic_version":"bedrock-2023-05-31",
+	"tools":[
+		{
+			"name":"get_weather",
+			"description":"Get the current weather in a given location",
+			"input_schema":{
+				"type":"object",
+				"properties":{"location":{"type":"string"}},
+				"required":["location"]
+			}
+		}
+	],
+	"tool_choice":{"type":"auto"}
+}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"id":"msg_000000000000000000000000",
+	"type":"message",
+	"role":"assistant",
+	"content":[
+		{"type":"text","text":"大阪も調べます。"},
+		{"type":"tool_use","id":"toolu_02","name":"get_weather","input":{"location":"Osaka"}}
+	],
+	"model":"claude-3-haiku-48k-20240307",
+	"stop_reason":"tool_use",
+	"stop_sequence":null,
+	"usage":{
+		"input_tokens":300,
+		"output_tokens":40
+	}
+}`)}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "東京の天気は？"),
+		{
+			Role: llms.ChatMessageTypeAI,
+			Parts: []llms.ContentPart{
+				llms.ToolCall{
+					ID:   "toolu_01",
+					Type: "function",
+					FunctionCall: &llms.FunctionCall{
+						Name:      "get_weather",
+						Arguments: `{"location":"Tokyo"}`,
+					},
+				},
+			},
+		},
+		{
+			Role: llms.ChatMessageTypeTool,
+			Parts: []llms.ContentPart{
+				llms.ToolCallResponse{
+					ToolCallID: "toolu_01",
+					Name:       "get_weather",
+					Content:    "晴れ",
+				},
+			},
+		},
+	}, llms.WithTools([]llms.Tool{
+		{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        "get_weather",
+				Description: "Get the current weather in a given location",
+				Parameters: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"location": map[string]any{"type": "string"},
+					},
+					"required": []string{"location"},
+				},
+			},
+		},
+	}), llms.WithToolChoice("auto"))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	require.Equal(t, "大阪も調べます。", resp.Choices[0].Content)
+	require.Equal(t, "tool_use", resp.Choices[0].StopReason)
+	require.EqualValues(t, []llms.ToolCall{
+		{
+			ID:   "toolu_02",
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      "get_weather",
+				Arguments: `{"location":"Osaka"}`,
+			},
+		},
+	}, resp.Choices[0].ToolCalls)
+	require.Equal(t, "get_weather", resp.Choices[0].FuncCall.Name)
+}
+
+func TestMockGenerateContentWithConverse(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("Converse", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.ConverseInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.Claude3Haiku {
+				return false
+			}
+			if !assert.EqualValues(t, []types.SystemContentBlock{
+				&types.SystemContentBlockMemberText{Value: "あなたは画像を読み取るアシスタントです。"},
+			}, input.System) {
+				return false
+			}
+			if !assert.EqualValues(t, &types.InferenceConfiguration{
+				MaxTokens:   aws.Int32(1000),
+				Temperature: aws.Float32(0.1),
+				TopP:        aws.Float32(0.9),
+			}, input.InferenceConfig) {
+				return false
+			}
+			additionalFields, err := input.AdditionalModelRequestFields.MarshalSmithyDocument()
+			if !assert.NoError(t, err) || !assert.JSONEq(t, `{"top_k":50}`, string(additionalFields)) {
+				return false
+			}
+			if !assert.EqualValues(t, []types.Message{
+				{
+					Role: types.ConversationRoleUser,
+					Content: []types.ContentBlock{
+						&types.ContentBlockMemberImage{
+							Value: types.ImageBlock{
+								Format: types.ImageFormatPng,
+								Source: &types.ImageSourceMemberBytes{Value: image},
+							},
+						},
+						&types.ContentBlockMemberText{Value: "この画像に書かれてる単語は何？"},
+					},
+				},
+			}, input.Messages) {
+				return false
+			}
+			return input.ToolConfig == nil
+		}),
+	).Return(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Role: types.ConversationRoleAssistant,
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberText{Value: "この画像に書かれている単語は \"LGTM\" です。"},
+				},
+			},
+		},
+		StopReason: types.StopReasonEndTurn,
+		Usage: &types.TokenUsage{
+			InputTokens:  aws.Int32(140),
+			OutputTokens: aws.Int32(22),
+			TotalTokens:  aws.Int32(162),
+		},
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithTemperature(0.1),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+		bedrock.WithConverseAPI(true),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "あなたは画像を読み取るアシスタントです。"),
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.BinaryPart("image/png", image),
+				llms.TextPart("この画像に書かれてる単語は何？"),
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Contains(t, resp.Choices[0].Content, "LGTM")
+	require.Equal(t, "end_turn", resp.Choices[0].StopReason)
+	require.Equal(t, 140, resp.Choices[0].GenerationInfo["usage.input_tokens"])
+	require.Equal(t, 22, resp.Choices[0].GenerationInfo["usage.output_tokens"])
+}
+
+func TestMockGenerateContentWithConverseTitanText(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("Converse", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.ConverseInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.TitanTextExpress &&
+				assert.EqualValues(t, []string{"User:"}, input.InferenceConfig.StopSequences) &&
+				assert.Nil(t, input.AdditionalModelRequestFields)
+		}),
+	).Return(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Role: types.ConversationRoleAssistant,
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberText{Value: "Hello!"},
+				},
+			},
+		},
+		StopReason: types.StopReasonEndTurn,
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.TitanTextExpress),
+		bedrock.WithStopWords([]string{"Human:"}),
+		bedrock.WithConverseAPI(true),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", resp.Choices[0].Content)
+}
+
+func TestMockGenerateContentWithConverseStreamToolUse(t *testing.T) {
+	client, httpClient := newStreamClient(t,
+		streamEvent{eventType: "messageStart", payload: `{"role":"assistant"}`},
+		streamEvent{eventType: "contentBlockDelta", payload: `{"contentBlockIndex":0,"delta":{"text":"調べます。"}}`},
+		streamEvent{eventType: "contentBlockStop", payload: `{"contentBlockIndex":0}`},
+		streamEvent{eventType: "contentBlockStart", payload: `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"tooluse_01","name":"get_weather"}}}`},
+		streamEvent{eventType: "contentBlockDelta", payload: `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"location\":"}}}`},
+		streamEvent{eventType: "contentBlockDelta", payload: `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"Tokyo\"}"}}}`},
+		streamEvent{eventType: "contentBlockStop", payload: `{"contentBlockIndex":1}`},
+		streamEvent{eventType: "messageStop", payload: `{"stopReason":"tool_use"}`},
+		streamEvent{eventType: "metadata", payload: `{"usage":{"inputTokens":100,"outputTokens":30,"totalTokens":130},"metrics":{"latencyMs":500}}`},
+	)
+	llm, err := bedrock.New(
+		bedrock.WithClient(client),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+		bedrock.WithConverseAPI(true),
+	)
+	require.NoError(t, err)
+	var chunks []string
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "東京の天気は？"),
+	}, llms.WithTools([]llms.Tool{
+		{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        "get_weather",
+				Description: "Get the current weather in a given location",
+				Parameters: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"location": map[string]any{"type": "string"},
+					},
+				},
+			},
+		},
+	}), llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	}))
+	require.NoError(t, err)
+	require.Len(t, httpClient.bodies, 1)
+	var body struct {
+		ToolConfig json.RawMessage `json:"toolConfig"`
+	}
+	require.NoError(t, json.Unmarshal(httpClient.bodies[0], &body))
+	require.JSONEq(t, `{"tools":[{"toolSpec":{"description":"Get the current weather in a given location","inputSchema":{"json":{"type":"object","properties":{"location":{"type":"string"}}}},"name":"get_weather"}}]}`, string(body.ToolConfig))
+	require.EqualValues(t, []string{"調べます。"}, chunks)
+	require.Equal(t, "調べます。", resp.Choices[0].Content)
+	require.Equal(t, "tool_use", resp.Choices[0].StopReason)
+	require.EqualValues(t, []llms.ToolCall{
+		{
+			ID:   "tooluse_01",
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      "get_weather",
+				Arguments: `{"location":"Tokyo"}`,
+			},
+		},
+	}, resp.Choices[0].ToolCalls)
+	require.Equal(t, 130, resp.Choices[0].GenerationInfo["usage.total_tokens"])
+}
+
+// invokeModelOnlyClient is a BedrockClient that implements none of the optional interfaces.
+type invokeModelOnlyClient struct {
+	bedrock.BedrockClient
+}
+
+func TestMockGenerateContentWithInvokeModelOnlyClient(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(invokeModelOnlyClient{m}),
+		bedrock.WithModel(bedrock.Claude2),
+		bedrock.WithRetryPolicy(bedrock.DefaultRetryPolicy()),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	resp, err := llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+
+	_, err = llm.GenerateContent(context.Background(), messages, llms.WithStreamingFunc(func(_ context.Context, _ []byte) error {
+		return nil
+	}))
+	require.ErrorIs(t, err, bedrock.ErrValidation)
+	require.ErrorContains(t, err, "does not implement InvokeModelWithResponseStream")
+
+	llm, err = bedrock.New(
+		bedrock.WithClient(invokeModelOnlyClient{m}),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+		bedrock.WithConverseAPI(true),
+	)
+	require.NoError(t, err)
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.ErrorIs(t, err, bedrock.ErrValidation)
+	require.ErrorContains(t, err, "does not implement Converse")
+}
+
+func TestMockGenerateContentWithLlama(t *testing.T) {
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	}
+	cases := []struct {
+		model  string
+		prompt string
+	}{
+		{
+			model:  bedrock.Llama2Chat13B,
+			prompt: "<s>[INST] <<SYS>>\nYou are a helpful assistant.\n<</SYS>>\n\nHello [/INST] Hi! How can I help you? </s><s>[INST] What is the heaviest hippo ever recorded? [/INST]",
+		},
+		{
+			model: bedrock.Llama3Instruct8B,
+			prompt: "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nYou are a helpful assistant.<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nHello<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\nHi! How can I help you?<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nWhat is the heaviest hippo ever recorded?<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.model, func(t *testing.T) {
+			m := newMockBedrockClient(t)
+			m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+				func(input *bedrockruntime.InvokeModelInput) bool {
+					if aws.ToString(input.ModelId) != c.model {
+						return false
+					}
+					var payload bedrock.LlamaRequest
+					if err := json.Unmarshal(input.Body, &payload); err != nil {
+						return false
+					}
+					return assert.Equal(t, bedrock.LlamaRequest{
+						Prompt:      c.prompt,
+						MaxGenLen:   512,
+						Temperature: 0.7,
+						TopP:        0.9,
+					}, payload)
+				}),
+			).Return(&bedrockruntime.InvokeModelOutput{
+				Body: []byte(`{"generation":"I don't know.","prompt_token_count":50,"generation_token_count":5,"stop_reason":"stop"}`),
+			}, nil).Times(1)
+			defer m.AssertExpectations(t)
+
+			llm, err := bedrock.New(
+				bedrock.WithClient(m),
+				bedrock.WithLogger(slog.Default()),
+				bedrock.WithModel(c.model),
+			)
+			require.NoError(t, err)
+			resp, err := llm.GenerateContent(context.Background(), messages, llms.WithMaxTokens(512))
+			require.NoError(t, err)
+			require.Equal(t, "I don't know.", resp.Choices[0].Content)
+			require.Equal(t, "stop", resp.Choices[0].StopReason)
+			require.Equal(t, 50, resp.Choices[0].GenerationInfo["usage.input_tokens"])
+			require.Equal(t, 5, resp.Choices[0].GenerationInfo["usage.output_tokens"])
+		})
+	}
+}
+
+func TestMockGenerateContentWithMistral(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.Mixtral8x7BInstruct {
+				return false
+			}
+			var payload bedrock.MistralRequest
+			if err := json.Unmarshal(input.Body, &payload); err != nil {
+				return false
+			}
+			return assert.Equal(t, bedrock.MistralRequest{
+				Prompt:      "<s>[INST] You are a helpful assistant.\n\nHello [/INST]Hi! How can I help you?</s>[INST] What is the heaviest hippo ever recorded? [/INST]",
+				MaxTokens:   1000,
+				Stop:        []string{"</s>"},
+				Temperature: 0.7,
+				TopP:        0.8,
+				TopK:        20,
+			}, payload)
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"outputs":[{"text":"I don't know.","stop_reason":"stop"},{"text":"Maybe 4,500 kg.","stop_reason":"length"}]}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.Mixtral8x7BInstruct),
+		bedrock.WithTopK(20),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	}, llms.WithTopP(0.8), llms.WithStopWords([]string{"</s>"}))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 2)
+	require.Equal(t, "I don't know.", resp.Choices[0].Content)
+	require.Equal(t, "stop", resp.Choices[0].StopReason)
+	require.Equal(t, "Maybe 4,500 kg.", resp.Choices[1].Content)
+	require.Equal(t, "length", resp.Choices[1].StopReason)
+}
+
+func TestMockGenerateContentWithTitanText(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.TitanTextExpress {
+				return false
+			}
+			return assert.JSONEq(t, `{
+	"inputText":"You are a helpful assistant.\n\nUser: Hello\nBot: Hi! How can I help you?\nUser: What is the heaviest hippo ever recorded?\nBot:",
+	"textGenerationConfig":{
+		"maxTokenCount":1000,
+		"stopSequences":["User:"],
+		"temperature":0.2,
+		"topP":0.9
+	}
+}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"inputTextTokenCount":40,"results":[{"tokenCount":6,"outputText":" I don't know.","completionReason":"FINISH"}]}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.TitanTextExpress),
+		bedrock.WithTemperature(0.2),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	require.Equal(t, " I don't know.", resp.Choices[0].Content)
+	require.Equal(t, "FINISH", resp.Choices[0].StopReason)
+	require.Equal(t, 40, resp.Choices[0].GenerationInfo["usage.input_tokens"])
+	require.Equal(t, 6, resp.Choices[0].GenerationInfo["usage.output_tokens"])
+}
+
+func TestMockGenerateContentWithCohereCommand(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.CohereCommandLight {
+				return false
+			}
+			return assert.JSONEq(t, `{
+	"prompt":"You are a helpful assistant.\n\nUser: Hello\nChatbot: Hi! How can I help you?\nUser: What is the heaviest hippo ever recorded?\nChatbot:",
+	"max_tokens":1000,
+	"temperature":0.7,
+	"p":0.9,
+	"k":50,
+	"stop_sequences":["Human:"],
+	"num_generations":2
+}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"id":"resp-01",
+	"prompt":"",
+	"generations":[
+		{"id":"gen-01","text":" It weighed 4,500 kg.","finish_reason":"COMPLETE"},
+		{"id":"gen-02","text":" I don't know.","finish_reason":"MAX_TOKENS"}
+	]
+}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.CohereCommandLight),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	}, llms.WithCandidateCount(2))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 2)
+	require.Equal(t, " It weighed 4,500 kg.", resp.Choices[0].Content)
+	require.Equal(t, "COMPLETE", resp.Choices[0].StopReason)
+	require.Equal(t, "gen-01", resp.Choices[0].GenerationInfo["id"])
+	require.Equal(t, " I don't know.", resp.Choices[1].Content)
+	require.Equal(t, "MAX_TOKENS", resp.Choices[1].StopReason)
+	require.Equal(t, "gen-02", resp.Choices[1].GenerationInfo["id"])
+}
+
+func TestMockGenerateContentWithCohereCommandR(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.CohereCommandR {
+				return false
+			}
+			return assert.JSONEq(t, `{
+	"message":"What is the heaviest hippo ever recorded?",
+	"chat_history":[
+		{"role":"USER","message":"Hello"},
+		{"role":"CHATBOT","message":"Hi! How can I help you?"}
+	],
+	"preamble":"You are a helpful assistant.",
+	"documents":[
+		{"title":"Hippopotamus","snippet":"The heaviest recorded hippo weighed 4,500 kg."}
+	],
+	"max_tokens":1000,
+	"temperature":0.7,
+	"p":0.9,
+	"k":50,
+	"stop_sequences":["Human:"]
+}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"response_id":"resp-01",
+	"generation_id":"gen-01",
+	"text":"It weighed 4,500 kg.",
+	"finish_reason":"COMPLETE",
+	"chat_history":[],
+	"citations":[{"start":11,"end":19,"text":"4,500 kg","document_ids":["doc_0"]}]
+}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithModel(bedrock.CohereCommandR),
+	)
+	require.NoError(t, err)
+	ctx := bedrock.ContextWithCohereDocuments(context.Background(), []bedrock.CohereDocument{
+		{"title": "Hippopotamus", "snippet": "The heaviest recorded hippo weighed 4,500 kg."},
+	})
+	resp, err := llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi! How can I help you?"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the heaviest hippo ever recorded?"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "It weighed 4,500 kg.", resp.Choices[0].Content)
+	require.Equal(t, "COMPLETE", resp.Choices[0].StopReason)
+	require.EqualValues(t, []bedrock.CohereCitation{
+		{Start: 11, End: 19, Text: "4,500 kg", DocumentIDs: []string{"doc_0"}},
+	}, resp.Choices[0].GenerationInfo["citations"])
+}
+
+func TestMockGenerateContentWithAI21(t *testing.T) {
+	t.Run("Jurassic-2", func(t *testing.T) {
+		m := newMockBedrockClient(t)
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				if aws.ToString(input.ModelId) != bedrock.AI21J2Mid {
+					return false
+				}
+				return assert.JSONEq(t, `{
+	"prompt":"You are a helpful assistant.\n\nUser: Hello\nAssistant:",
+	"maxTokens":1000,
+	"temperature":0.7,
+	"topP":0.9,
+	"stopSequences":["Human:"],
+	"countPenalty":{"scale":0.5},
+	"presencePenalty":{"scale":0.2},
+	"frequencyPenalty":{"scale":0.3}
+}`, string(input.Body))
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(`{
+	"id":1234,
+	"completions":[{"data":{"text":" Hi! How can I help you?"},"finishReason":{"reason":"endoftext"}}]
+}`),
+		}, nil).Times(1)
+		defer m.AssertExpectations(t)
+
+		llm, err := bedrock.New(
+			bedrock.WithClient(m),
+			bedrock.WithLogger(slog.Default()),
+			bedrock.WithModel(bedrock.AI21J2Mid),
+			bedrock.WithFrequencyPenalty(0.3),
+		)
+		require.NoError(t, err)
+		resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+			llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		}, llms.WithRepetitionPenalty(0.5), llms.WithPresencePenalty(0.2))
+		require.NoError(t, err)
+		require.Equal(t, " Hi! How can I help you?", resp.Choices[0].Content)
+		require.Equal(t, "endoftext", resp.Choices[0].StopReason)
+	})
+	t.Run("Jamba", func(t *testing.T) {
+		m := newMockBedrockClient(t)
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				if aws.ToString(input.ModelId) != bedrock.AI21JambaInstruct {
+					return false
+				}
+				return assert.JSONEq(t, `{
+	"messages":[
+		{"role":"system","content":"You are a helpful assistant."},
+		{"role":"user","content":"Hello"}
+	],
+	"max_tokens":1000,
+	"temperature":0.7,
+	"top_p":0.9,
+	"stop":["Human:"],
+	"frequency_penalty":0.3
+}`, string(input.Body))
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(`{
+	"id":"chatcmpl-01",
+	"choices":[{"index":0,"message":{"role":"assistant","content":"Hi! How can I help you?"},"finish_reason":"stop"}],
+	"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}
+}`),
+		}, nil).Times(1)
+		defer m.AssertExpectations(t)
+
+		llm, err := bedrock.New(
+			bedrock.WithClient(m),
+			bedrock.WithLogger(slog.Default()),
+			bedrock.WithModel(bedrock.AI21JambaInstruct),
+			bedrock.WithFrequencyPenalty(0.3),
+		)
+		require.NoError(t, err)
+		resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+			llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Hi! How can I help you?", resp.Choices[0].Content)
+		require.Equal(t, "stop", resp.Choices[0].StopReason)
+		require.EqualValues(t, 12, resp.Choices[0].GenerationInfo["usage.input_tokens"])
+		require.EqualValues(t, 8, resp.Choices[0].GenerationInfo["usage.output_tokens"])
+	})
+}
+
+func TestMockCreateEmbeddingWithTitanEmbedTextV2(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.TitanEmbedTextV2 {
+				return false
+			}
+			return assert.JSONEq(t, `{"inputText":"this is a pen","dimensions":512,"normalize":false}`, string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 5}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithEmbeddingModel(bedrock.TitanEmbedTextV2),
+		bedrock.WithEmbeddingDimensions(512),
+		bedrock.WithEmbeddingNormalize(false),
+	)
+	require.NoError(t, err)
+	resp, err := llm.CreateEmbeddingWithUsage(context.Background(), []string{
+		"this is a pen",
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, [][]float32{{0.1, 0.2, 0.3}}, resp.Embeddings)
+	require.EqualValues(t, []int{5}, resp.InputTokenCounts)
+	require.Equal(t, 5, resp.TotalInputTokenCount())
+
+	llm, err = bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithEmbeddingModel(bedrock.TitanEmbedTextV2),
+		bedrock.WithEmbeddingDimensions(1536),
+	)
+	require.NoError(t, err)
+	_, err = llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	require.Error(t, err)
+}
+
+func TestMockCreateEmbeddingWithCohereEmbed(t *testing.T) {
+	texts := make([]string, 100)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("これは%d番目の文書です", i)
+	}
+	m := newMockBedrockClient(t)
+	for _, batch := range [][]string{texts[:96], texts[96:]} {
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				if aws.ToString(input.ModelId) != bedrock.CohereEmbedMultilingualV3 {
+					return false
+				}
+				var payload struct {
+					Texts     []string `json:"texts"`
+					InputType string   `json:"input_type"`
+				}
+				if err := json.Unmarshal(input.Body, &payload); err != nil {
+					return false
+				}
+				return payload.InputType == bedrock.CohereEmbedInputTypeSearchQuery && assert.ObjectsAreEqual(batch, payload.Texts)
+			}),
+		).Return(func() *bedrockruntime.InvokeModelOutput {
+			embeddings := make([][]float64, len(batch))
+			for i := range batch {
+				embeddings[i] = []float64{0.1, 0.2}
+			}
+			body, err := json.Marshal(map[string]interface{}{
+				"id":            "emb-01",
+				"response_type": "embeddings_floats",
+				"embeddings":    embeddings,
+				"texts":         batch,
+			})
+			require.NoError(t, err)
+			return &bedrockruntime.InvokeModelOutput{Body: body}
+		}(), nil).Times(1)
+	}
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithEmbeddingModel(bedrock.CohereEmbedMultilingualV3),
+	)
+	require.NoError(t, err)
+	ctx := bedrock.ContextWithEmbeddingInputType(context.Background(), bedrock.CohereEmbedInputTypeSearchQuery)
+	embeddings, err := llm.CreateEmbedding(ctx, texts)
+	require.NoError(t, err)
+	require.Len(t, embeddings, 100)
+	require.EqualValues(t, []float32{0.1, 0.2}, embeddings[99])
+}
+
+func TestMockCreateMultimodalEmbeddingWithTitan(t *testing.T) {
+	image := []byte("\x89PNG\r\n\x1a\n")
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			if aws.ToString(input.ModelId) != bedrock.TitanEmbedImageV1 {
+				return false
+			}
+			return assert.JSONEq(t, fmt.Sprintf(`{
+	"inputText":"a pen",
+	"inputImage":"%s",
+	"embeddingConfig":{"outputEmbeddingLength":384}
+}`, base64.StdEncoding.EncodeToString(image)), string(input.Body))
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 3}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithEmbeddingModel(bedrock.TitanEmbedImageV1),
+		bedrock.WithEmbeddingDimensions(384),
+	)
+	require.NoError(t, err)
+	embeddings, err := llm.CreateMultimodalEmbedding(context.Background(), [][]llms.ContentPart{
+		{
+			llms.TextPart("a pen"),
+			llms.BinaryPart("image/png", image),
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, [][]float32{{0.1, 0.2, 0.3}}, embeddings)
+}
+
+type imageGenerationRecorder struct {
+	callbacks.SimpleHandler
+	events []string
+}
+
+func (r *imageGenerationRecorder) HandleImageGenerationStart(_ context.Context, input *bedrock.ImageGenerationInput) {
+	r.events = append(r.events, "start: "+input.Prompt)
+}
+
+func (r *imageGenerationRecorder) HandleImageGenerationEnd(_ context.Context, output *bedrock.ImageGenerationOutput) {
+	r.events = append(r.events, fmt.Sprintf("end: %d images", len(output.Images)))
+}
+
+func (r *imageGenerationRecorder) HandleImageGenerationError(_ context.Context, err error) {
+	r.events = append(r.events, "error: "+err.Error())
+}
+
+func TestMockGenerateImage(t *testing.T) {
+	image := []byte("\x89PNG\r\n\x1a\n")
+	encoded := base64.StdEncoding.EncodeToString(image)
+	t.Run("StableDiffusionXL", func(t *testing.T) {
+		m := newMockBedrockClient(t)
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				if aws.ToString(input.ModelId) != bedrock.StableDiffusionXL {
+					return false
+				}
+				return assert.JSONEq(t, `{
+	"text_prompts":[{"text":"a hippo","weight":1},{"text":"blurry","weight":-1}],
+	"cfg_scale":7,
+	"seed":42,
+	"steps":30,
+	"style_preset":"photographic"
+}`, string(input.Body))
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(fmt.Sprintf(`{"result":"success","artifacts":[{"seed":42,"base64":"%s","finishReason":"SUCCESS"}]}`, encoded)),
+		}, nil).Times(1)
+		defer m.AssertExpectations(t)
+
+		llm, err := bedrock.New(
+			bedrock.WithClient(m),
+			bedrock.WithImageModel(bedrock.StableDiffusionXL),
+		)
+		require.NoError(t, err)
+		output, err := llm.GenerateImage(context.Background(), &bedrock.ImageGenerationInput{
+			Prompt:         "a hippo",
+			NegativePrompt: "blurry",
+			CFGScale:       7,
+			Seed:           42,
+			Steps:          30,
+			StylePreset:    "photographic",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, []*bedrock.GeneratedImage{
+			{Data: image, Seed: 42, FinishReason: "SUCCESS"},
+		}, output.Images)
+	})
+	t.Run("TitanImageGenerator", func(t *testing.T) {
+		m := newMockBedrockClient(t)
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				if aws.ToString(input.ModelId) != bedrock.TitanImageGeneratorV1 {
+					return false
+				}
+				return assert.JSONEq(t, fmt.Sprintf(`{
+	"taskType":"INPAINTING",
+	"inPaintingParams":{"image":"%s","text":"a hippo","maskPrompt":"the dog"},
+	"imageGenerationConfig":{"numberOfImages":1,"seed":7}
+}`, encoded), string(input.Body))
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(fmt.Sprintf(`{"images":["%s"]}`, encoded)),
+		}, nil).Times(1)
+		defer m.AssertExpectations(t)
+
+		llm, err := bedrock.New(bedrock.WithClient(m))
+		require.NoError(t, err)
+		recorder := &imageGenerationRecorder{}
+		llm.CallbacksHandler = recorder
+		output, err := llm.GenerateImage(context.Background(), &bedrock.ImageGenerationInput{
+			TaskType:       bedrock.ImageTaskTypeInpainting,
+			Prompt:         "a hippo",
+			InputImages:    [][]byte{image},
+			MaskPrompt:     "the dog",
+			NumberOfImages: 1,
+			Seed:           7,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, []*bedrock.GeneratedImage{
+			{Data: image},
+		}, output.Images)
+
+		_, err = llm.GenerateImage(context.Background(), &bedrock.ImageGenerationInput{
+			TaskType: bedrock.ImageTaskTypeInpainting,
+		})
+		require.EqualError(t, err, "no input image")
+		require.Equal(t, []string{
+			"start: a hippo",
+			"end: 1 images",
+			"start: ",
+			"error: no input image",
+		}, recorder.events)
+
+		_, err = llm.GenerateImage(context.Background(), nil)
+		require.ErrorIs(t, err, bedrock.ErrValidation)
+	})
+}
+
+func TestMockGenerateContentWithModelOverride(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.Claude2 &&
+				strings.Contains(string(input.Body), `"max_tokens_to_sample"`)
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	resp, err := llm.GenerateContent(context.Background(), messages, llms.WithModel(bedrock.Claude2))
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+	require.Equal(t, bedrock.Claude2, resp.Choices[0].GenerationInfo["model"])
+
+	_, err = llm.GenerateContent(context.Background(), messages, llms.WithModel("unknown.model-v1"))
+	require.EqualError(t, err, "model `unknown.model-v1` not supported")
+}
+
+func TestMockGenerateContentWithRegisteredModel(t *testing.T) {
+	const customModel = "anthropic.claude-3-haiku-20991231-v1:0"
+	require.NoError(t, bedrock.RegisterModel(bedrock.ModelInfo{
+		ID:              customModel,
+		Family:          bedrock.ModelFamilyClaude3,
+		MaxOutputTokens: 4096,
+		SupportsSystem:  true,
+	}))
+	info, ok := bedrock.LookupModel(customModel)
+	require.True(t, ok)
+	require.Equal(t, bedrock.ModelFamilyClaude3, info.Family)
+
+	const newVersionModel = "anthropic.claude-3-haiku-20991232-v1:0"
+	require.NoError(t, bedrock.RegisterModelLike(newVersionModel, bedrock.Claude3Haiku))
+	baseInfo, ok := bedrock.LookupModel(bedrock.Claude3Haiku)
+	require.True(t, ok)
+	info, ok = bedrock.LookupModel(newVersionModel)
+	require.True(t, ok)
+	require.Equal(t, newVersionModel, info.ID)
+	baseInfo.ID = newVersionModel
+	require.Equal(t, baseInfo, info)
+	require.True(t, info.SupportsImages && info.SupportsTools && info.SupportsSystem && info.SupportsStreaming)
+	require.Error(t, bedrock.RegisterModelLike(newVersionModel, "unknown.model-v1"))
+
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == customModel &&
+				strings.Contains(string(input.Body), `"anthropic_version"`)
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"id":"msg_01",
+	"type":"message",
+	"role":"assistant",
+	"content":[{"type":"text","text":"Hello!"}],
+	"model":"claude-3-haiku-20991231",
+	"stop_reason":"end_turn",
+	"usage":{"input_tokens":10,"output_tokens":2}
+}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(customModel),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", resp.Choices[0].Content)
+
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{llms.BinaryPart("image/png", []byte("\x89PNG\r\n\x1a\n"))},
+		},
+	})
+	require.EqualError(t, err, "model `"+customModel+"` does not support images")
+
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}, llms.WithMaxTokens(8192))
+	require.Error(t, err)
+
+	streamingFunc := llms.WithStreamingFunc(func(_ context.Context, _ []byte) error {
+		return nil
+	})
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}, streamingFunc)
+	require.ErrorIs(t, err, bedrock.ErrValidation)
+	require.EqualError(t, err, "model `"+customModel+"` does not support streaming")
+
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}, streamingFunc, llms.WithModel(bedrock.Llama3Instruct8B))
+	require.EqualError(t, err, "model `"+bedrock.Llama3Instruct8B+"` does not support streaming")
+}
+
+func TestMockGenerateContentWithModelARN(t *testing.T) {
+	const provisionedARN = "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abcdefghijkl"
+	const foundationARN = "arn:aws:bedrock:us-east-1::foundation-model/" + bedrock.Claude2
+	m := newMockBedrockClient(t)
+	for _, modelID := range []string{provisionedARN, foundationARN} {
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				return aws.ToString(input.ModelId) == modelID &&
+					strings.Contains(string(input.Body), `"max_tokens_to_sample"`)
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+		}, nil).Times(2)
+	}
+	const inferenceProfileProvisionedARN = "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/mnopqrstuvwx"
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == inferenceProfileProvisionedARN &&
+				strings.Contains(string(input.Body), `"anthropic_version"`)
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"id":"msg_01","type":"message","role":"assistant","content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModelAlias(inferenceProfileProvisionedARN, bedrock.USClaude3Haiku),
+		bedrock.WithModelARN(provisionedARN, bedrock.ClaudeInstant),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	resp, err := llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+	require.Equal(t, provisionedARN, resp.Choices[0].GenerationInfo["model"])
+
+	_, err = llm.GenerateContent(context.Background(), messages, llms.WithModel(foundationARN))
+	require.NoError(t, err)
+
+	resp, err = llm.GenerateContent(context.Background(), messages, llms.WithModel(inferenceProfileProvisionedARN))
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", resp.Choices[0].Content)
+
+	_, err = llm.GenerateContent(context.Background(), messages, llms.WithModel("arn:aws:bedrock:us-east-1:123456789012:custom-model/unknown"))
+	require.Error(t, err)
+
+	// WithModelAlias does not change the model set before it.
+	llm, err = bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(foundationARN),
+		bedrock.WithModelAlias(provisionedARN, bedrock.ClaudeInstant),
+	)
+	require.NoError(t, err)
+	resp, err = llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, foundationARN, resp.Choices[0].GenerationInfo["model"])
+	resp, err = llm.GenerateContent(context.Background(), messages, llms.WithModel(provisionedARN))
+	require.NoError(t, err)
+	require.Equal(t, provisionedARN, resp.Choices[0].GenerationInfo["model"])
+}
+
+func TestMockGenerateContentWithInferenceProfile(t *testing.T) {
+	modelIDs := []string{
+		bedrock.USClaude3Haiku,
+		bedrock.APACClaude3Sonnet,
+		"arn:aws:bedrock:eu-west-1:123456789012:inference-profile/" + bedrock.EUClaude3Haiku,
+	}
+	m := newMockBedrockClient(t)
+	for _, modelID := range modelIDs {
+		m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+			func(input *bedrockruntime.InvokeModelInput) bool {
+				return aws.ToString(input.ModelId) == modelID &&
+					strings.Contains(string(input.Body), `"anthropic_version"`)
+			}),
+		).Return(&bedrockruntime.InvokeModelOutput{
+			Body: []byte(`{
+	"id":"msg_01",
+	"type":"message",
+	"role":"assistant",
+	"content":[{"type":"text","text":"Hello!"}],
+	"stop_reason":"end_turn",
+	"usage":{"input_tokens":10,"output_tokens":2}
+}`),
+		}, nil).Times(1)
+	}
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(bedrock.WithClient(m))
+	require.NoError(t, err)
+	for _, modelID := range modelIDs {
+		resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		}, llms.WithModel(modelID))
+		require.NoError(t, err, modelID)
+		require.Equal(t, "Hello!", resp.Choices[0].Content)
+	}
+}
+
+// createEmbeddingWithTimeout calls CreateEmbedding and fails the test if it does not return within a few seconds.
+func createEmbeddingWithTimeout(t *testing.T, llm *bedrock.LLM, texts []string) error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := llm.CreateEmbedding(context.Background(), texts)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("CreateEmbedding did not return")
+		return nil
+	}
+}
+
+func TestMockCreateEmbeddingWithFailingWorkers(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ValidationException{Message: aws.String("Malformed input request")},
+	)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithNumWorkers(2),
+	)
+	require.NoError(t, err)
+	texts := make([]string, 20)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	err = createEmbeddingWithTimeout(t, llm, texts)
+	var validationErr *types.ValidationException
+	require.ErrorAs(t, err, &validationErr)
+}
+
+func TestMockCreateEmbeddingWithRetry(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ThrottlingException{Message: aws.String("Too many requests")},
+	).Times(2)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 4}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	policy := bedrock.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithLogger(slog.Default()),
+		bedrock.WithRetryPolicy(policy),
+	)
+	require.NoError(t, err)
+	embeddings, err := llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	require.NoError(t, err)
+	require.EqualValues(t, [][]float32{{0.1, 0.2, 0.3}}, embeddings)
+
+	m.On("InvokeModel", mock.Anything, mock.Anything).Unset()
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ValidationException{Message: aws.String("Malformed input request")},
+	).Times(1)
+	_, err = llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	var validationErr *types.ValidationException
+	require.ErrorAs(t, err, &validationErr)
+}
+
+// throttlingHTTPClient is a fake HTTP client for bedrockruntime.Client, that always returns ThrottlingException.
+type throttlingHTTPClient struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *throttlingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Content-Type":     []string{"application/json"},
+			"X-Amzn-Errortype": []string{"ThrottlingException"},
+		},
+		Body:    io.NopCloser(strings.NewReader(`{"message":"Too many requests"}`)),
+		Request: req,
+	}, nil
+}
+
+func TestMockCreateEmbeddingWithPartialRetryPolicy(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ThrottlingException{Message: aws.String("Too many requests")},
+	).Times(4)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 4}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	// MaxAttempts, MaxDelay and RetryableErrorCodes are filled with the values of DefaultRetryPolicy.
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithRetryPolicy(bedrock.RetryPolicy{BaseDelay: time.Millisecond}),
+	)
+	require.NoError(t, err)
+	embeddings, err := llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	require.NoError(t, err)
+	require.EqualValues(t, [][]float32{{0.1, 0.2, 0.3}}, embeddings)
+}
+
+func TestMockCreateEmbeddingWithRetryExhausted(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ThrottlingException{Message: aws.String("Too many requests")},
+	)
+	defer m.AssertExpectations(t)
+
+	policy := bedrock.DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.BaseDelay = time.Millisecond
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithNumWorkers(2),
+		bedrock.WithRetryPolicy(policy),
+	)
+	require.NoError(t, err)
+	texts := make([]string, 20)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	err = createEmbeddingWithTimeout(t, llm, texts)
+	require.ErrorIs(t, err, bedrock.ErrThrottled)
+}
+
+func TestCreateEmbeddingWithRetryDisablesSDKRetries(t *testing.T) {
+	httpClient := &throttlingHTTPClient{}
+	policy := bedrock.DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.BaseDelay = time.Millisecond
+	llm, err := bedrock.New(
+		bedrock.WithAWSConfig(aws.Config{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  httpClient,
+		}),
+		bedrock.WithRetryPolicy(policy),
+	)
+	require.NoError(t, err)
+	_, err = llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	require.ErrorIs(t, err, bedrock.ErrThrottled)
+	require.Equal(t, 2, httpClient.calls)
+}
+
+func TestMockGenerateContentErrors(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ThrottlingException{Message: aws.String("Too many requests")},
+	).Once()
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ValidationException{Message: aws.String("prompt is too long: 210000 tokens > 200000 maximum")},
+	).Once()
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ValidationException{Message: aws.String("Malformed input request")},
+	).Once()
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	for _, expected := range []error{bedrock.ErrThrottled, bedrock.ErrContextWindowExceeded, bedrock.ErrValidation} {
+		_, err = llm.GenerateContent(context.Background(), messages)
+		require.ErrorIs(t, err, expected)
+		var apiErr smithy.APIError
+		require.ErrorAs(t, err, &apiErr)
+	}
+
+	_, err = llm.GenerateContent(context.Background(), messages, llms.WithModel("unknown.model-v1"))
+	require.ErrorIs(t, err, bedrock.ErrUnsupportedModel)
+	var bedrockErr *bedrock.Error
+	require.ErrorAs(t, err, &bedrockErr)
+	require.Equal(t, "model `unknown.model-v1` not supported", bedrockErr.Error())
+
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{llms.TextPart("Hello"), llms.ToolCallResponse{ToolCallID: "1", Name: "f", Content: "{}"}},
+		},
+	}, llms.WithModel(bedrock.Llama3Instruct8B))
+	require.ErrorIs(t, err, bedrock.ErrUnsupportedContent)
+
+	require.ErrorIs(t, bedrock.StopReasonError("max_tokens"), bedrock.ErrMaxTokensReached)
+	require.ErrorIs(t, bedrock.StopReasonError("CONTENT_FILTERED"), bedrock.ErrContentFiltered)
+	require.NoError(t, bedrock.StopReasonError("end_turn"))
+}
+
+func TestMockCreateEmbeddingWithRateLimit(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 4}`),
+	}, nil).Times(4)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithRateLimit(bedrock.TitanEmbeddingG1Text, bedrock.RateLimit{RequestsPerSecond: 20}),
+	)
+	require.NoError(t, err)
+	start := time.Now()
+	embeddings, err := llm.CreateEmbedding(context.Background(), []string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	require.Len(t, embeddings, 4)
+	require.GreaterOrEqual(t, time.Since(start), 140*time.Millisecond)
+}
+
+func TestMockGenerateContentWithTokenRateLimit(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"id":"msg_01","type":"message","role":"assistant","content":[{"type":"text","text":"LGTM"}],"stop_reason":"end_turn","usage":{"input_tokens":1500,"output_tokens":2}}`),
+	}, nil).Times(2)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+		bedrock.WithRateLimit(bedrock.Claude3Haiku, bedrock.RateLimit{TokensPerMinute: 1000}),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.BinaryPart("image/png", bytes.Repeat([]byte("\x89PNG\r\n\x1a\n"), 64*1024)),
+				llms.TextPart("この画像に書かれてる単語は何？"),
+			},
+		},
+	}
+	// the image is not counted as tokens, so the calls do not wait for the token bucket.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for range 2 {
+		_, err := llm.GenerateContent(ctx, messages)
+		require.NoError(t, err)
+	}
+}
+
+type circuitBreakerRecorder struct {
+	callbacks.SimpleHandler
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (r *circuitBreakerRecorder) HandleCircuitBreakerStateChange(_ context.Context, modelID string, from, to bedrock.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, modelID+": "+from.String()+" -> "+to.String())
+}
+
+func TestMockGenerateContentWithCircuitBreaker(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ServiceUnavailableException{Message: aws.String("Service unavailable")},
+	).Times(2)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithCircuitBreaker(bedrock.CircuitBreakerPolicy{
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      2,
+			Window:               time.Minute,
+			OpenTimeout:          50 * time.Millisecond,
+			HalfOpenMaxRequests:  1,
+		}),
+	)
+	require.NoError(t, err)
+	recorder := &circuitBreakerRecorder{}
+	llm.CallbacksHandler = recorder
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	for i := 0; i < 2; i++ {
+		_, err = llm.GenerateContent(context.Background(), messages)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, bedrock.ErrCircuitOpen)
+	}
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.ErrorIs(t, err, bedrock.ErrCircuitOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	resp, err := llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+	require.Equal(t, []string{
+		bedrock.ClaudeInstant + ": closed -> open",
+		bedrock.ClaudeInstant + ": open -> half-open",
+		bedrock.ClaudeInstant + ": half-open -> closed",
+	}, recorder.transitions)
+}
+
+func TestMockGenerateContentWithPartialCircuitBreakerPolicy(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(3)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ServiceUnavailableException{Message: aws.String("Service unavailable")},
+	).Times(3)
+	defer m.AssertExpectations(t)
+
+	// zero fields are filled with DefaultCircuitBreakerPolicy: 50% failures in a minute.
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithCircuitBreaker(bedrock.CircuitBreakerPolicy{MinimumRequests: 3}),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	for i := 0; i < 3; i++ {
+		_, err = llm.GenerateContent(context.Background(), messages)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err = llm.GenerateContent(context.Background(), messages)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, bedrock.ErrCircuitOpen)
+	}
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.ErrorIs(t, err, bedrock.ErrCircuitOpen)
+}
+
+func TestMockGenerateContentWithCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	success := &bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}
+	promptContains := func(text string) any {
+		return mock.MatchedBy(func(input *bedrockruntime.InvokeModelInput) bool {
+			return strings.Contains(string(input.Body), text)
+		})
+	}
+	slowStarted, releaseSlow := make(chan struct{}), make(chan struct{})
+	probeStarted, releaseProbe := make(chan struct{}), make(chan struct{})
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, promptContains("slow")).Run(func(mock.Arguments) {
+		close(slowStarted)
+		<-releaseSlow
+	}).Return(success, nil).Times(1)
+	m.On("InvokeModel", mock.Anything, promptContains("fail")).Return(
+		nil, &types.ServiceUnavailableException{Message: aws.String("Service unavailable")},
+	).Times(2)
+	m.On("InvokeModel", mock.Anything, promptContains("probe")).Run(func(mock.Arguments) {
+		close(probeStarted)
+		<-releaseProbe
+	}).Return(success, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithCircuitBreaker(bedrock.CircuitBreakerPolicy{
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      2,
+			Window:               time.Minute,
+			OpenTimeout:          50 * time.Millisecond,
+			HalfOpenMaxRequests:  1,
+		}),
+	)
+	require.NoError(t, err)
+	recorder := &circuitBreakerRecorder{}
+	llm.CallbacksHandler = recorder
+	generate := func(text string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			// send in defer, since an unexpected call to the mock exits the goroutine.
+			err := errors.New("generate content did not return")
+			defer func() { done <- err }()
+			_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+				llms.TextParts(llms.ChatMessageTypeHuman, text),
+			})
+		}()
+		return done
+	}
+
+	// a slow request allowed while closed finishes after the circuit opened and half-opened.
+	slowDone := generate("slow")
+	<-slowStarted
+	for i := 0; i < 2; i++ {
+		require.Error(t, <-generate("fail"))
+	}
+	time.Sleep(60 * time.Millisecond)
+	probeDone := generate("probe")
+	<-probeStarted
+	close(releaseSlow)
+	require.NoError(t, <-slowDone)
+
+	// the slow result does not close the circuit, while the probe is still running.
+	require.ErrorIs(t, <-generate("rejected"), bedrock.ErrCircuitOpen)
+
+	close(releaseProbe)
+	require.NoError(t, <-probeDone)
+	require.Equal(t, []string{
+		bedrock.ClaudeInstant + ": closed -> open",
+		bedrock.ClaudeInstant + ": open -> half-open",
+		bedrock.ClaudeInstant + ": half-open -> closed",
+	}, recorder.transitions)
+}
+
+func TestMockGenerateContentWithCircuitBreakerIgnoresCallerDeadline(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithRateLimit(bedrock.ClaudeInstant, bedrock.RateLimit{RequestsPerSecond: 0.1}),
+		bedrock.WithCircuitBreaker(bedrock.CircuitBreakerPolicy{
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      1,
+		}),
+	)
+	require.NoError(t, err)
+	recorder := &circuitBreakerRecorder{}
+	llm.CallbacksHandler = recorder
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+
+	// the deadline of the caller expires while waiting for the rate limit, which is not a failure of the model.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = llm.GenerateContent(ctx, messages)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Empty(t, recorder.transitions)
+}
+
+func TestMockGenerateContentWithCircuitBreakerIgnoresCancelledProbe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ServiceUnavailableException{Message: aws.String("Service unavailable")},
+	).Times(2)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		cancel()
+	}).Return(nil, context.Canceled).Times(1)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Times(1)
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithCircuitBreaker(bedrock.CircuitBreakerPolicy{
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      2,
+			OpenTimeout:          50 * time.Millisecond,
+			HalfOpenMaxRequests:  1,
+		}),
+	)
+	require.NoError(t, err)
+	recorder := &circuitBreakerRecorder{}
+	llm.CallbacksHandler = recorder
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	for i := 0; i < 2; i++ {
+		_, err = llm.GenerateContent(context.Background(), messages)
+		require.Error(t, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// the cancelled probe neither closes nor opens the circuit, and releases the slot for the next probe.
+	_, err = llm.GenerateContent(ctx, messages)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []string{
+		bedrock.ClaudeInstant + ": closed -> open",
+		bedrock.ClaudeInstant + ": open -> half-open",
+	}, recorder.transitions)
+
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		bedrock.ClaudeInstant + ": closed -> open",
+		bedrock.ClaudeInstant + ": open -> half-open",
+		bedrock.ClaudeInstant + ": half-open -> closed",
+	}, recorder.transitions)
+}
+
+func TestMockGenerateContentWithRegionFailover(t *testing.T) {
+	primary := newMockBedrockClient(t)
+	primary.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ThrottlingException{Message: aws.String("Too many requests")},
+	).Once()
+	primary.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ResourceNotFoundException{Message: aws.String("Model not found")},
+	).Once()
+	primary.On("InvokeModel", mock.Anything, mock.Anything).Return(
+		nil, &types.ValidationException{Message: aws.String("Malformed input request")},
+	).Once()
+	defer primary.AssertExpectations(t)
+	secondary := newMockBedrockClient(t)
+	secondary.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.ClaudeInstant
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Once()
+	secondary.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.TitanEmbeddingG1Text
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 4}`),
+	}, nil).Once()
+	defer secondary.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithRegionClient("ap-northeast-1", primary),
+		bedrock.WithRegionClient("us-east-1", secondary),
+	)
+	require.NoError(t, err)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	resp, err := llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+	require.Equal(t, "us-east-1", resp.Choices[0].GenerationInfo["region"])
+
+	embeddings, err := llm.CreateEmbedding(context.Background(), []string{"this is a pen"})
+	require.NoError(t, err)
+	require.EqualValues(t, [][]float32{{0.1, 0.2, 0.3}}, embeddings)
+
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.ErrorIs(t, err, bedrock.ErrValidation)
+}
+
+func TestMockGenerateContentWithRegionFailoverErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		failover bool
+	}{
+		{
+			name:     "network error",
+			err:      &smithyhttp.RequestSendError{Err: errors.New("connection reset by peer")},
+			failover: true,
+		},
+		{
+			name: "validation error of the package",
+			err:  bedrock.ErrValidation,
+		},
+		{
+			name: "unknown error",
+			err:  errors.New("failed to marshal payload"),
+		},
+		{
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			primary := newMockBedrockClient(t)
+			primary.On("InvokeModel", mock.Anything, mock.Anything).Return(nil, c.err).Once()
+			defer primary.AssertExpectations(t)
+			secondary := newMockBedrockClient(t)
+			if c.failover {
+				secondary.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+					Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+				}, nil).Once()
+			}
+			defer secondary.AssertExpectations(t)
+
+			llm, err := bedrock.New(
+				bedrock.WithRegionClient("ap-northeast-1", primary),
+				bedrock.WithRegionClient("us-east-1", secondary),
+			)
+			require.NoError(t, err)
+			_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{
+				llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+			})
+			if c.failover {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, c.err)
+			}
+		})
+	}
+}
+
+func TestMockGenerateContentWithFallbackModels(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.Claude3Sonnet
+		}),
+	).Return(nil, &types.ThrottlingException{Message: aws.String("Too many requests")}).Once()
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.Claude3Haiku
+		}),
+	).Return(nil, &types.ValidationException{Message: aws.String("prompt is too long: 210000 tokens > 200000 maximum")}).Once()
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.ClaudeInstant &&
+				strings.Contains(string(input.Body), `"max_tokens_to_sample"`)
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"completion":" Hello!","stop_reason":"stop_sequence","stop":"\n\nHuman:"}`),
+	}, nil).Once()
+	defer m.AssertExpectations(t)
+
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.Claude3Sonnet),
+		bedrock.WithFallbackModels(bedrock.Claude3Haiku, bedrock.ClaudeInstant),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, " Hello!", resp.Choices[0].Content)
+	require.Equal(t, bedrock.ClaudeInstant, resp.Choices[0].GenerationInfo["model"])
+}
+
+func TestMockUsageTracker(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.Claude3Haiku
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"id":"msg_01",
+	"type":"message",
+	"role":"assistant",
+	"content":[{"type":"text","text":"Hello!"}],
+	"stop_reason":"end_turn",
+	"usage":{"input_tokens":1000,"output_tokens":2000}
+}`),
+	}, nil).Twice()
+	m.On("InvokeModel", mock.Anything, mock.MatchedBy(
+		func(input *bedrockruntime.InvokeModelInput) bool {
+			return aws.ToString(input.ModelId) == bedrock.TitanEmbeddingG1Text
+		}),
+	).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"embedding": [0.1, 0.2, 0.3], "inputTextTokenCount": 500}`),
+	}, nil).Twice()
+	defer m.AssertExpectations(t)
+
+	tracker := bedrock.NewUsageTracker(nil)
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.Claude3Haiku),
+		bedrock.WithUsageTracker(tracker),
+	)
+	require.NoError(t, err)
+	featureTracker := bedrock.NewUsageTracker(bedrock.PriceTable{
+		bedrock.Claude3Haiku: {InputPer1KTokens: 1, OutputPer1KTokens: 2},
+	})
+	ctx := bedrock.ContextWithUsageTracker(context.Background(), featureTracker)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}
+	_, err = llm.GenerateContent(ctx, messages)
+	require.NoError(t, err)
+	_, err = llm.GenerateContent(context.Background(), messages)
+	require.NoError(t, err)
+	_, err = llm.CreateEmbedding(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+
+	snapshot := tracker.Snapshot()
+	require.Equal(t, 2, snapshot[bedrock.Claude3Haiku].Requests)
+	require.Equal(t, 2000, snapshot[bedrock.Claude3Haiku].InputTokens)
+	require.Equal(t, 4000, snapshot[bedrock.Claude3Haiku].OutputTokens)
+	require.InDelta(t, 0.0055, snapshot[bedrock.Claude3Haiku].CostUSD, 1e-9)
+	require.Equal(t, 1000, snapshot[bedrock.TitanEmbeddingG1Text].InputTokens)
+	require.InDelta(t, 0.0001, snapshot[bedrock.TitanEmbeddingG1Text].CostUSD, 1e-9)
+	require.InDelta(t, 0.0056, tracker.TotalCostUSD(), 1e-9)
+
+	require.Equal(t, map[string]bedrock.ModelUsage{
+		bedrock.Claude3Haiku: {Requests: 1, InputTokens: 1000, OutputTokens: 2000, CostUSD: 5},
+	}, featureTracker.Snapshot())
+
+	tracker.Reset()
+	require.Empty(t, tracker.Snapshot())
+}
+
+// tokenCountHTTPClient is a fake HTTP client for bedrockruntime.Client, that returns the body with the token count headers of InvokeModel.
+type tokenCountHTTPClient struct {
+	body         string
+	inputTokens  string
+	outputTokens string
+}
+
+func (c *tokenCountHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":                      []string{"application/json"},
+			"X-Amzn-Bedrock-Input-Token-Count":  []string{c.inputTokens},
+			"X-Amzn-Bedrock-Output-Token-Count": []string{c.outputTokens},
+		},
+		Body:    io.NopCloser(strings.NewReader(c.body)),
+		Request: req,
+	}, nil
+}
+
+func TestUsageTrackerWithTokenCountHeaders(t *testing.T) {
+	cases := []struct {
+		name         string
+		httpClient   *tokenCountHTTPClient
+		call         func(ctx context.Context, llm *bedrock.LLM) error
+		modelID      string
+		inputTokens  int
+		outputTokens int
+		cost         float64
+	}{
+		{
+			name: "claude instant",
+			httpClient: &tokenCountHTTPClient{
+				body:         `{"completion":" Hello!","stop_reason":"stop_sequence"}`,
+				inputTokens:  "1000",
+				outputTokens: "2000",
+			},
+			call: func(ctx context.Context, llm *bedrock.LLM) error {
+				_, err := llm.GenerateContent(ctx, []llms.MessageContent{
+					llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+				}, llms.WithModel(bedrock.ClaudeInstant))
+				return err
+			},
+			modelID:      bedrock.ClaudeInstant,
+			inputTokens:  1000,
+			outputTokens: 2000,
+			cost:         0.0056,
+		},
+		{
+			name: "cohere embed",
+			httpClient: &tokenCountHTTPClient{
+				body:         `{"embeddings":[[0.1,0.2],[0.3,0.4]],"id":"emb_01","response_type":"embeddings_floats","texts":["a","b"]}`,
+				inputTokens:  "3000",
+				outputTokens: "0",
+			},
+			call: func(ctx context.Context, llm *bedrock.LLM) error {
+				_, err := llm.CreateEmbedding(ctx, []string{"a", "b"})
+				return err
+			},
+			modelID:     bedrock.CohereEmbedEnglishV3,
+			inputTokens: 3000,
+			cost:        0.0003,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tracker := bedrock.NewUsageTracker(nil)
+			llm, err := bedrock.New(
+				bedrock.WithAWSConfig(aws.Config{
+					Region:      "us-east-1",
+					Credentials: aws.AnonymousCredentials{},
+					HTTPClient:  c.httpClient,
+				}),
+				bedrock.WithEmbeddingModel(bedrock.CohereEmbedEnglishV3),
+				bedrock.WithUsageTracker(tracker),
+			)
+			require.NoError(t, err)
+			require.NoError(t, c.call(context.Background(), llm))
+			usage := tracker.Snapshot()[c.modelID]
+			require.Equal(t, 1, usage.Requests)
+			require.Equal(t, c.inputTokens, usage.InputTokens)
+			require.Equal(t, c.outputTokens, usage.OutputTokens)
+			require.InDelta(t, c.cost, usage.CostUSD, 1e-9)
+		})
+	}
+}
+
+func TestMockUsageTrackerWithTitanTextResults(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"inputTextTokenCount": 100,
+	"results": [
+		{"tokenCount": 200, "outputText": "Hello!", "completionReason": "FINISH"},
+		{"tokenCount": 300, "outputText": "Hi!", "completionReason": "FINISH"}
+	]
+}`),
+	}, nil).Once()
+	defer m.AssertExpectations(t)
+
+	tracker := bedrock.NewUsageTracker(nil)
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.TitanTextExpress),
+		bedrock.WithUsageTracker(tracker),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 2)
+	usage := tracker.Snapshot()[bedrock.TitanTextExpress]
+	require.Equal(t, 1, usage.Requests)
+	require.Equal(t, 100, usage.InputTokens)
+	require.Equal(t, 500, usage.OutputTokens)
+}
+
+func TestMockUsageTrackerWithJambaChoices(t *testing.T) {
+	m := newMockBedrockClient(t)
+	m.On("InvokeModel", mock.Anything, mock.Anything).Return(&bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{
+	"id":"chatcmpl-01",
+	"choices":[
+		{"index":0,"message":{"role":"assistant","content":"Hi!"},"finish_reason":"stop"},
+		{"index":1,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}
+	],
+	"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}
+}`),
+	}, nil).Once()
+	defer m.AssertExpectations(t)
+
+	tracker := bedrock.NewUsageTracker(nil)
+	llm, err := bedrock.New(
+		bedrock.WithClient(m),
+		bedrock.WithModel(bedrock.AI21JambaInstruct),
+		bedrock.WithUsageTracker(tracker),
+	)
+	require.NoError(t, err)
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+	}, llms.WithCandidateCount(2))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 2)
+	require.NotContains(t, resp.Choices[1].GenerationInfo, "usage.output_tokens")
+	usage := tracker.Snapshot()[bedrock.AI21JambaInstruct]
+	require.Equal(t, 12, usage.InputTokens)
+	require.Equal(t, 8, usage.OutputTokens)
+}
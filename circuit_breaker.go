@@ -0,0 +1,301 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerPolicy configures the circuit breaker of each model ID.
+// Zero fields are filled with the values of DefaultCircuitBreakerPolicy.
+type CircuitBreakerPolicy struct {
+	// FailureRateThreshold is the failure rate (0.0-1.0) in the window that opens the circuit.
+	FailureRateThreshold float64
+	// MinimumRequests is the number of requests in the window required before the failure rate is evaluated.
+	MinimumRequests int
+	// Window is the duration after which the request and failure counts are reset while closed.
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before it half-opens to probe recovery.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probe requests allowed while half-open.
+	// The circuit closes when all of them succeed, and opens again on any failure.
+	HalfOpenMaxRequests int
+}
+
+// DefaultCircuitBreakerPolicy returns a CircuitBreakerPolicy that opens at 50% failures of at least 10 requests in a minute.
+func DefaultCircuitBreakerPolicy() CircuitBreakerPolicy {
+	return CircuitBreakerPolicy{
+		FailureRateThreshold: 0.5,
+		MinimumRequests:      10,
+		Window:               time.Minute,
+		OpenTimeout:          30 * time.Second,
+		HalfOpenMaxRequests:  1,
+	}
+}
+
+// withDefaults returns the policy with zero fields filled with the values of DefaultCircuitBreakerPolicy.
+func (p CircuitBreakerPolicy) withDefaults() CircuitBreakerPolicy {
+	d := DefaultCircuitBreakerPolicy()
+	if p.FailureRateThreshold <= 0 {
+		p.FailureRateThreshold = d.FailureRateThreshold
+	}
+	if p.MinimumRequests <= 0 {
+		p.MinimumRequests = d.MinimumRequests
+	}
+	if p.Window <= 0 {
+		p.Window = d.Window
+	}
+	if p.OpenTimeout <= 0 {
+		p.OpenTimeout = d.OpenTimeout
+	}
+	if p.HalfOpenMaxRequests <= 0 {
+		p.HalfOpenMaxRequests = d.HalfOpenMaxRequests
+	}
+	return p
+}
+
+// CircuitBreakerHandler is an optional interface of LLM.CallbacksHandler to be notified of circuit breaker state changes.
+type CircuitBreakerHandler interface {
+	HandleCircuitBreakerStateChange(ctx context.Context, modelID string, from, to CircuitState)
+}
+
+// circuitBreakerResult is the result of a request recorded by the circuit breaker.
+type circuitBreakerResult int
+
+const (
+	circuitBreakerSuccess circuitBreakerResult = iota
+	circuitBreakerFailure
+	// circuitBreakerIgnored is a request cancelled or timed out by the caller, that tells nothing about the service.
+	circuitBreakerIgnored
+)
+
+// classifyCircuitBreakerResult classifies the result of a request by whether err indicates that the service is degraded.
+// Client errors other than throttling are successes, and errors after the caller's context is done,
+// including the wait of the rate limiter, are ignored.
+func classifyCircuitBreakerResult(ctx context.Context, err error) circuitBreakerResult {
+	if err == nil {
+		return circuitBreakerSuccess
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return circuitBreakerIgnored
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultClient && apiErr.ErrorCode() != "ThrottlingException" {
+		return circuitBreakerSuccess
+	}
+	return circuitBreakerFailure
+}
+
+type circuitBreaker struct {
+	mu               sync.Mutex
+	policy           CircuitBreakerPolicy
+	state            CircuitState
+	windowStart      time.Time
+	requests         int
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	halfOpenSuccess  int
+	// generation is incremented on every state change, so that results of requests allowed in a previous state are ignored.
+	generation uint64
+}
+
+func newCircuitBreaker(policy CircuitBreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{
+		policy:      policy,
+		windowStart: time.Now(),
+	}
+}
+
+// setState changes the state and returns the previous state.
+func (b *circuitBreaker) setState(state CircuitState, now time.Time) CircuitState {
+	from := b.state
+	b.state = state
+	b.generation++
+	switch state {
+	case CircuitClosed:
+		b.windowStart = now
+		b.requests = 0
+		b.failures = 0
+	case CircuitOpen:
+		b.openedAt = now
+	case CircuitHalfOpen:
+		b.halfOpenInFlight = 0
+		b.halfOpenSuccess = 0
+	}
+	return from
+}
+
+// allow reports whether a request is allowed with the generation to record its result, and the state transitions that happened.
+func (b *circuitBreaker) allow(now time.Time) (bool, uint64, [][2]CircuitState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var transitions [][2]CircuitState
+	switch b.state {
+	case CircuitClosed:
+		if now.Sub(b.windowStart) > b.policy.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+		return true, b.generation, nil
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.policy.OpenTimeout {
+			return false, b.generation, nil
+		}
+		transitions = append(transitions, [2]CircuitState{b.setState(CircuitHalfOpen, now), CircuitHalfOpen})
+	}
+	if b.halfOpenInFlight >= b.policy.HalfOpenMaxRequests {
+		return false, b.generation, transitions
+	}
+	b.halfOpenInFlight++
+	return true, b.generation, transitions
+}
+
+// record records the result of a request allowed in the generation, and returns the state transitions that happened.
+// Results of requests allowed before the last state change are ignored.
+// An ignored result only releases the half-open slot of the request.
+func (b *circuitBreaker) record(generation uint64, result circuitBreakerResult, now time.Time) [][2]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return nil
+	}
+	switch b.state {
+	case CircuitClosed:
+		if result == circuitBreakerIgnored {
+			return nil
+		}
+		b.requests++
+		if result == circuitBreakerFailure {
+			b.failures++
+		}
+		if b.requests >= b.policy.MinimumRequests && float64(b.failures)/float64(b.requests) >= b.policy.FailureRateThreshold {
+			return [][2]CircuitState{{b.setState(CircuitOpen, now), CircuitOpen}}
+		}
+	case CircuitHalfOpen:
+		b.halfOpenInFlight--
+		switch result {
+		case circuitBreakerIgnored:
+			return nil
+		case circuitBreakerFailure:
+			return [][2]CircuitState{{b.setState(CircuitOpen, now), CircuitOpen}}
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.policy.HalfOpenMaxRequests {
+			return [][2]CircuitState{{b.setState(CircuitClosed, now), CircuitClosed}}
+		}
+	}
+	return nil
+}
+
+// circuitBreakerClient is a BedrockClient that fails fast with ErrCircuitOpen while the circuit of the model is open.
+type circuitBreakerClient struct {
+	client        BedrockClient
+	policy        CircuitBreakerPolicy
+	onStateChange func(ctx context.Context, modelID string, from, to CircuitState)
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+var (
+	_ BedrockClient               = (*circuitBreakerClient)(nil)
+	_ BedrockStreamClient         = (*circuitBreakerClient)(nil)
+	_ BedrockConverseClient       = (*circuitBreakerClient)(nil)
+	_ BedrockConverseStreamClient = (*circuitBreakerClient)(nil)
+)
+
+func newCircuitBreakerClient(client BedrockClient, policy CircuitBreakerPolicy, onStateChange func(ctx context.Context, modelID string, from, to CircuitState)) *circuitBreakerClient {
+	return &circuitBreakerClient{
+		client:        client,
+		policy:        policy.withDefaults(),
+		onStateChange: onStateChange,
+		breakers:      make(map[string]*circuitBreaker),
+	}
+}
+
+func (c *circuitBreakerClient) breaker(modelID string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[modelID]
+	if !ok {
+		b = newCircuitBreaker(c.policy)
+		c.breakers[modelID] = b
+	}
+	return b
+}
+
+func (c *circuitBreakerClient) notify(ctx context.Context, modelID string, transitions [][2]CircuitState) {
+	for _, t := range transitions {
+		c.onStateChange(ctx, modelID, t[0], t[1])
+	}
+}
+
+func withCircuitBreaker[In, Out any](ctx context.Context, c *circuitBreakerClient, op operation[In, Out], modelID *string, params In, optFns []func(*bedrockruntime.Options)) (Out, error) {
+	var zero Out
+	if op.err != nil {
+		return zero, op.err
+	}
+	id := aws.ToString(modelID)
+	b := c.breaker(id)
+	allowed, generation, transitions := b.allow(time.Now())
+	c.notify(ctx, id, transitions)
+	if !allowed {
+		return zero, newError(ErrCircuitOpen, "circuit breaker is open for model `%s`", id)
+	}
+	output, err := op.call(ctx, params, optFns...)
+	c.notify(ctx, id, b.record(generation, classifyCircuitBreakerResult(ctx, err), time.Now()))
+	return output, err
+}
+
+func (c *circuitBreakerClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	return withCircuitBreaker(ctx, c, invokeModelOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *circuitBreakerClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return withCircuitBreaker(ctx, c, invokeModelWithResponseStreamOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *circuitBreakerClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	return withCircuitBreaker(ctx, c, converseOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *circuitBreakerClient) ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error) {
+	return withCircuitBreaker(ctx, c, converseStreamOperation(c.client), params.ModelId, params, optFns)
+}
+
+// handleCircuitBreakerStateChange reports the state change through the logger and CallbacksHandler.
+func (l *LLM) handleCircuitBreakerStateChange(ctx context.Context, modelID string, from, to CircuitState) {
+	l.logger.Warn("circuit breaker state changed", "model", modelID, "from", from.String(), "to", to.String())
+	if h, ok := l.CallbacksHandler.(CircuitBreakerHandler); ok {
+		h.HandleCircuitBreakerStateChange(ctx, modelID, from, to)
+	}
+}
@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -23,41 +21,61 @@ type Claude2Request struct {
 
 type Claude2Response struct {
 	Completion string `json:"completion"`
+	StopReason string `json:"stop_reason"`
+	Stop       string `json:"stop"`
 }
 
-func (l *LLM) generateContentWithClaude2(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
-	if len(messages) != 1 {
-		return nil, errors.New("only one message is supported")
-	}
-	if opts.MaxTokens == 0 {
-		opts.MaxTokens = l.maxTokens
-	}
-	if opts.Temperature == 0 {
-		opts.Temperature = l.temperature
-	}
-	if opts.TopP == 0 {
-		opts.TopP = l.topP
-	}
-	if opts.TopK == 0 {
-		opts.TopK = l.topK
+// renderClaude2Prompt renders messages into the Claude 2 text completion prompt.
+// A single human message is used as is, so that an already formatted prompt can be passed.
+//
+//	{system}\n\nHuman: {user}\n\nAssistant: {assistant}\n\nHuman: {user}\n\nAssistant:
+func renderClaude2Prompt(messages []llms.MessageContent) (string, error) {
+	if len(messages) == 1 && messages[0].Role == llms.ChatMessageTypeHuman {
+		prompt, err := joinTextParts(messages[0])
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(prompt, "\n\nHuman:") {
+			prompt = "\n\nHuman:" + prompt
+		}
+		if !strings.Contains(prompt, "\n\nAssistant:") {
+			prompt = prompt + "\n\nAssistant:"
+		}
+		return prompt, nil
 	}
-	if opts.StopWords == nil {
-		opts.StopWords = l.stopWords
+	var builder strings.Builder
+	var lastRole llms.ChatMessageType
+	for _, message := range messages {
+		text, err := joinTextParts(message)
+		if err != nil {
+			return "", err
+		}
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			builder.WriteString(strings.TrimSpace(text))
+		case llms.ChatMessageTypeHuman:
+			builder.WriteString("\n\nHuman: " + strings.TrimSpace(text))
+		case llms.ChatMessageTypeAI:
+			builder.WriteString("\n\nAssistant: " + strings.TrimSpace(text))
+		default:
+			return "", fmt.Errorf("unsupported role: %s", message.Role)
+		}
+		lastRole = message.Role
 	}
-	msg := messages[0]
-	if len(msg.Parts) != 1 {
-		return nil, errors.New("only one part is supported")
+	if lastRole != llms.ChatMessageTypeAI {
+		builder.WriteString("\n\nAssistant:")
 	}
-	textPart, ok := msg.Parts[0].(llms.TextContent)
-	if !ok {
-		return nil, errors.New("only text content is supported")
-	}
-	prompt := textPart.Text
-	if !strings.Contains(prompt, "\n\nHuman:") {
-		prompt = "\n\nHuman:" + prompt
+	return builder.String(), nil
+}
+
+func (l *LLM) generateContentWithClaude2(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
 	}
-	if !strings.Contains(prompt, "\n\nAssistant:") {
-		prompt = prompt + "\n\nAssistant:"
+	l.applyDefaultCallOptions(opts)
+	prompt, err := renderClaude2Prompt(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
 	}
 
 	payload := Claude2Request{
@@ -73,27 +91,53 @@ func (l *LLM) generateContentWithClaude2(ctx context.Context, messages []llms.Me
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
-	output, err := l.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(opts.Model),
-		Body:        payloadBytes,
-		ContentType: aws.String("application/json"),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+	if opts.StreamingFunc != nil {
+		return l.generateContentWithClaude2Stream(ctx, payloadBytes, opts)
 	}
 	var resp Claude2Response
-	if err := json.Unmarshal(output.Body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := l.invokeModel(ctx, opts.Model, payloadBytes, &resp); err != nil {
+		return nil, err
+	}
+	return newContentResponseFromClaude2(&resp, opts), nil
+}
+
+func (l *LLM) generateContentWithClaude2Stream(ctx context.Context, payloadBytes []byte, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	var resp Claude2Response
+	var builder strings.Builder
+	err := l.invokeModelWithResponseStream(ctx, opts.Model, payloadBytes, func(chunk []byte) error {
+		var part Claude2Response
+		if err := json.Unmarshal(chunk, &part); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if part.StopReason != "" {
+			resp.StopReason = part.StopReason
+			resp.Stop = part.Stop
+		}
+		if part.Completion == "" {
+			return nil
+		}
+		builder.WriteString(part.Completion)
+		return opts.StreamingFunc(ctx, []byte(part.Completion))
+	})
+	if err != nil {
+		return nil, err
 	}
-	llmResponse := &llms.ContentResponse{
+	resp.Completion = builder.String()
+	l.logger.Debug("generate content stream with claude v2", "stop_reason", resp.StopReason, "stop", resp.Stop)
+	return newContentResponseFromClaude2(&resp, opts), nil
+}
+
+func newContentResponseFromClaude2(resp *Claude2Response, opts *llms.CallOptions) *llms.ContentResponse {
+	return &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{
 			{
-				Content: resp.Completion,
+				Content:    resp.Completion,
+				StopReason: resp.StopReason,
 				GenerationInfo: map[string]interface{}{
 					"model": opts.Model,
+					"stop":  resp.Stop,
 				},
 			},
 		},
 	}
-	return llmResponse, nil
 }
@@ -6,14 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/schema"
 )
 
 type Claude3Request struct {
@@ -25,6 +20,21 @@ type Claude3Request struct {
 	MaxTokens        int                      `json:"max_tokens,omitempty"`
 	Messages         []*Claude3RequestMessage `json:"messages,omitempty"`
 	AnthropicVersion string                   `json:"anthropic_version,omitempty"`
+	Tools            []Claude3Tool            `json:"tools,omitempty"`
+	ToolChoice       *Claude3ToolChoice       `json:"tool_choice,omitempty"`
+}
+
+// Claude3Tool is a tool definition for Claude3.
+// see: https://docs.anthropic.com/claude/docs/tool-use
+type Claude3Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type Claude3ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type Claude3RequestMessage struct {
@@ -50,6 +60,23 @@ type Claude3RequestMessageImageContent struct {
 
 func (Claude3RequestMessageImageContent) thisIslaudeV3RequestMessageContent() {}
 
+type Claude3RequestMessageToolUseContent struct {
+	Type  string          `json:"type,omitempty"`
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+func (Claude3RequestMessageToolUseContent) thisIslaudeV3RequestMessageContent() {}
+
+type Claude3RequestMessageToolResultContent struct {
+	Type      string `json:"type,omitempty"`
+	ToolUseID string `json:"tool_use_id"`
+	Content   string `json:"content,omitempty"`
+}
+
+func (Claude3RequestMessageToolResultContent) thisIslaudeV3RequestMessageContent() {}
+
 type claoudelV3RequestMessageImageContentSource struct {
 	Type      string `json:"type"`
 	MediaType string `json:"media_type"`
@@ -68,8 +95,11 @@ type Claude3Response struct {
 }
 
 type Claude3ResponseContent struct {
-	Text string `json:"text"`
-	Type string `json:"type"`
+	Text  string          `json:"text"`
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 type Claude3ResponseUsage struct {
@@ -77,15 +107,36 @@ type Claude3ResponseUsage struct {
 	OutputTokens int `json:"output_tokens"`
 }
 
+// Claude3StreamEvent is a event of InvokeModelWithResponseStream for Claude3.
+// see: https://docs.anthropic.com/claude/reference/messages-streaming
+type Claude3StreamEvent struct {
+	Type         string                  `json:"type"`
+	Index        int                     `json:"index"`
+	Message      *Claude3Response        `json:"message,omitempty"`
+	ContentBlock *Claude3ResponseContent `json:"content_block,omitempty"`
+	Delta        *Claude3StreamDelta     `json:"delta,omitempty"`
+	Usage        *Claude3ResponseUsage   `json:"usage,omitempty"`
+}
+
+type Claude3StreamDelta struct {
+	Type         string `json:"type"`
+	Text         string `json:"text"`
+	PartialJSON  string `json:"partial_json"`
+	StopReason   string `json:"stop_reason"`
+	StopSequence any    `json:"stop_sequence"`
+}
+
 func convertMessageForClaude3(message llms.MessageContent) (*Claude3RequestMessage, error) {
 	var role string
 	switch message.Role {
-	case schema.ChatMessageTypeHuman:
+	case llms.ChatMessageTypeHuman:
 		role = "user"
-	case schema.ChatMessageTypeAI:
+	case llms.ChatMessageTypeAI:
 		role = "assistant"
-	case schema.ChatMessageTypeSystem:
+	case llms.ChatMessageTypeSystem:
 		role = "system"
+	case llms.ChatMessageTypeTool:
+		role = "user"
 	default:
 		return nil, fmt.Errorf("unsupported role: %s", message.Role)
 	}
@@ -98,19 +149,10 @@ func convertMessageForClaude3(message llms.MessageContent) (*Claude3RequestMessa
 				Text: p.Text,
 			})
 		case llms.ImageURLContent:
-			resp, err := http.DefaultClient.Get(p.URL)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get image: %w", err)
-			}
-			if resp.StatusCode >= http.StatusNoContent {
-				return nil, fmt.Errorf("failed to get image: %s", resp.Status)
-			}
-			bs, err := io.ReadAll(resp.Body)
+			bs, mediaType, err := fetchImageURL(p.URL)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read image: %w", err)
+				return nil, err
 			}
-			resp.Body.Close()
-			mediaType := http.DetectContentType(bs)
 			content = append(content, Claude3RequestMessageImageContent{
 				Type: "image",
 				Source: claoudelV3RequestMessageImageContentSource{
@@ -128,8 +170,28 @@ func convertMessageForClaude3(message llms.MessageContent) (*Claude3RequestMessa
 					Data:      base64.StdEncoding.EncodeToString(p.Data),
 				},
 			})
+		case llms.ToolCall:
+			if p.FunctionCall == nil {
+				return nil, fmt.Errorf("tool call `%s` has no function call", p.ID)
+			}
+			input := json.RawMessage(p.FunctionCall.Arguments)
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			content = append(content, Claude3RequestMessageToolUseContent{
+				Type:  "tool_use",
+				ID:    p.ID,
+				Name:  p.FunctionCall.Name,
+				Input: input,
+			})
+		case llms.ToolCallResponse:
+			content = append(content, Claude3RequestMessageToolResultContent{
+				Type:      "tool_result",
+				ToolUseID: p.ToolCallID,
+				Content:   p.Content,
+			})
 		default:
-			return nil, fmt.Errorf("unsupported content type: %T", p)
+			return nil, newUnsupportedContentError(p)
 		}
 	}
 	return &Claude3RequestMessage{
@@ -145,27 +207,97 @@ func convertMessagesForClaude3(messages []llms.MessageContent) ([]*Claude3Reques
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert message: %w", err)
 		}
+		// Claude3 requires alternating user and assistant roles,
+		// so consecutive messages of the same role (e.g. multiple tool results) are merged into one.
+		if n := len(result); n > 0 && msg.Role != "system" && result[n-1].Role == msg.Role {
+			result[n-1].Content = append(result[n-1].Content, msg.Content...)
+			continue
+		}
 		result = append(result, msg)
 	}
 	return result, nil
 }
 
-func (l *LLM) generateContentWithClaude3(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
-	if opts.MaxTokens == 0 {
-		opts.MaxTokens = l.maxTokens
+func convertToolsForClaude3(opts *llms.CallOptions) []Claude3Tool {
+	var tools []Claude3Tool
+	for _, tool := range opts.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		tools = append(tools, newClaude3Tool(tool.Function))
+	}
+	for i := range opts.Functions {
+		tools = append(tools, newClaude3Tool(&opts.Functions[i]))
+	}
+	return tools
+}
+
+func setToolsForClaude3(payload *Claude3Request, opts *llms.CallOptions) error {
+	tools := convertToolsForClaude3(opts)
+	if len(tools) == 0 {
+		return nil
+	}
+	toolChoice, useTools, err := convertToolChoiceForClaude3(opts)
+	if err != nil {
+		return err
 	}
-	if opts.Temperature == 0 {
-		opts.Temperature = l.temperature
+	if useTools {
+		payload.Tools = tools
+		payload.ToolChoice = toolChoice
+	}
+	return nil
+}
+
+func newClaude3Tool(f *llms.FunctionDefinition) Claude3Tool {
+	inputSchema := f.Parameters
+	if inputSchema == nil {
+		inputSchema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{},
+		}
 	}
-	if opts.TopP == 0 {
-		opts.TopP = l.topP
+	return Claude3Tool{
+		Name:        f.Name,
+		Description: f.Description,
+		InputSchema: inputSchema,
 	}
-	if opts.TopK == 0 {
-		opts.TopK = l.topK
+}
+
+// convertToolChoiceForClaude3 converts llms.CallOptions.ToolChoice (or FunctionCallBehavior) into Claude3ToolChoice.
+// The second return value is false if tools should not be sent, i.e. tool choice is "none".
+func convertToolChoiceForClaude3(opts *llms.CallOptions) (*Claude3ToolChoice, bool, error) {
+	choice := opts.ToolChoice
+	if choice == nil && opts.FunctionCallBehavior != "" {
+		choice = string(opts.FunctionCallBehavior)
 	}
-	if opts.StopWords == nil {
-		opts.StopWords = l.stopWords
+	switch c := choice.(type) {
+	case nil:
+		return nil, true, nil
+	case string:
+		switch c {
+		case "", "auto":
+			return &Claude3ToolChoice{Type: "auto"}, true, nil
+		case "any", "required":
+			return &Claude3ToolChoice{Type: "any"}, true, nil
+		case "none":
+			return nil, false, nil
+		default:
+			return &Claude3ToolChoice{Type: "tool", Name: c}, true, nil
+		}
+	case llms.ToolChoice:
+		return convertToolChoiceForClaude3(&llms.CallOptions{ToolChoice: &c})
+	case *llms.ToolChoice:
+		if c.Function != nil && c.Function.Name != "" {
+			return &Claude3ToolChoice{Type: "tool", Name: c.Function.Name}, true, nil
+		}
+		return convertToolChoiceForClaude3(&llms.CallOptions{ToolChoice: c.Type})
+	default:
+		return nil, false, fmt.Errorf("unsupported tool choice: %T", c)
 	}
+}
+
+func (l *LLM) generateContentWithClaude3(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	l.applyDefaultCallOptions(opts)
 	payload := Claude3Request{
 		AnthropicVersion: "bedrock-2023-05-31",
 		Temperature:      opts.Temperature,
@@ -174,6 +306,9 @@ func (l *LLM) generateContentWithClaude3(ctx context.Context, messages []llms.Me
 		StopSequences:    opts.StopWords,
 		MaxTokens:        opts.MaxTokens,
 	}
+	if err := setToolsForClaude3(&payload, opts); err != nil {
+		return nil, err
+	}
 	msgs, err := convertMessagesForClaude3(messages)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert messages: %w", err)
@@ -241,28 +376,157 @@ func (l *LLM) generateContentWithClaude3(ctx context.Context, messages []llms.Me
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 	l.logger.Debug("generate content with claude v3", "payload", string(payloadBytes))
-	output, err := l.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(opts.Model),
-		Body:        payloadBytes,
-		ContentType: aws.String("application/json"),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+	if opts.StreamingFunc != nil {
+		return l.generateContentWithClaude3Stream(ctx, payloadBytes, opts)
 	}
 	var resp Claude3Response
-	if err := json.Unmarshal(output.Body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := l.invokeModel(ctx, opts.Model, payloadBytes, &resp); err != nil {
+		return nil, err
 	}
 	l.logger.Debug("generate content with claude v3", "id", resp.ID, "role", resp.Role, "stop_reason", resp.StopReason, "stop_sequence", resp.StopSequence, "type", resp.Type, "usage", resp.Usage)
+	return newContentResponseFromClaude3(&resp, opts), nil
+}
+
+func (l *LLM) generateContentWithClaude3Stream(ctx context.Context, payloadBytes []byte, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	acc := newClaude3StreamAccumulator()
+	err := l.invokeModelWithResponseStream(ctx, opts.Model, payloadBytes, func(chunk []byte) error {
+		var event Claude3StreamEvent
+		if err := json.Unmarshal(chunk, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		if event.Type == "error" {
+			return fmt.Errorf("received error event: %s", string(chunk))
+		}
+		text := acc.add(&event)
+		if text == "" {
+			return nil
+		}
+		return opts.StreamingFunc(ctx, []byte(text))
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &acc.resp
+	l.logger.Debug("generate content stream with claude v3", "id", resp.ID, "role", resp.Role, "stop_reason", resp.StopReason, "stop_sequence", resp.StopSequence, "type", resp.Type, "usage", resp.Usage)
+	return newContentResponseFromClaude3(resp, opts), nil
+}
+
+// claude3StreamAccumulator builds a Claude3Response from the events of the response stream.
+type claude3StreamAccumulator struct {
+	resp         Claude3Response
+	partialJSONs map[int]*strings.Builder
+}
+
+func newClaude3StreamAccumulator() *claude3StreamAccumulator {
+	return &claude3StreamAccumulator{
+		partialJSONs: make(map[int]*strings.Builder),
+	}
+}
+
+// add applies the event to the response, and returns the text delta to stream if any.
+func (a *claude3StreamAccumulator) add(event *Claude3StreamEvent) string {
+	switch event.Type {
+	case "message_start":
+		a.startMessage(event)
+	case "content_block_start":
+		a.startContentBlock(event)
+	case "content_block_delta":
+		return a.addContentBlockDelta(event)
+	case "content_block_stop":
+		a.stopContentBlock(event)
+	case "message_delta":
+		a.addMessageDelta(event)
+	}
+	return ""
+}
+
+func (a *claude3StreamAccumulator) startMessage(event *Claude3StreamEvent) {
+	if event.Message != nil {
+		a.resp = *event.Message
+	}
+}
+
+func (a *claude3StreamAccumulator) startContentBlock(event *Claude3StreamEvent) {
+	if event.ContentBlock != nil {
+		a.resp.Content = append(a.resp.Content, *event.ContentBlock)
+	}
+}
+
+func (a *claude3StreamAccumulator) addContentBlockDelta(event *Claude3StreamEvent) string {
+	if event.Delta == nil {
+		return ""
+	}
+	switch event.Delta.Type {
+	case "input_json_delta":
+		if _, ok := a.partialJSONs[event.Index]; !ok {
+			a.partialJSONs[event.Index] = &strings.Builder{}
+		}
+		a.partialJSONs[event.Index].WriteString(event.Delta.PartialJSON)
+		return ""
+	case "text_delta":
+		if event.Index < len(a.resp.Content) {
+			a.resp.Content[event.Index].Text += event.Delta.Text
+		} else {
+			a.resp.Content = append(a.resp.Content, Claude3ResponseContent{
+				Type: "text",
+				Text: event.Delta.Text,
+			})
+		}
+		return event.Delta.Text
+	default:
+		return ""
+	}
+}
+
+func (a *claude3StreamAccumulator) stopContentBlock(event *Claude3StreamEvent) {
+	if b, ok := a.partialJSONs[event.Index]; ok && event.Index < len(a.resp.Content) {
+		a.resp.Content[event.Index].Input = json.RawMessage(b.String())
+	}
+}
+
+func (a *claude3StreamAccumulator) addMessageDelta(event *Claude3StreamEvent) {
+	if event.Delta != nil {
+		a.resp.StopReason = event.Delta.StopReason
+		a.resp.StopSequence = event.Delta.StopSequence
+	}
+	if event.Usage != nil {
+		a.resp.Usage.OutputTokens = event.Usage.OutputTokens
+	}
+}
+
+func newContentResponseFromClaude3(resp *Claude3Response, opts *llms.CallOptions) *llms.ContentResponse {
 	var builder strings.Builder
+	var toolCalls []llms.ToolCall
 	for _, content := range resp.Content {
-		builder.WriteString(content.Text)
+		switch content.Type {
+		case "tool_use":
+			arguments := string(content.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:   content.ID,
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name:      content.Name,
+					Arguments: arguments,
+				},
+			})
+		default:
+			builder.WriteString(content.Text)
+		}
+	}
+	var funcCall *llms.FunctionCall
+	if len(toolCalls) > 0 {
+		funcCall = toolCalls[0].FunctionCall
 	}
 	llmResponse := &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{
 			{
 				Content:    builder.String(),
 				StopReason: resp.StopReason,
+				FuncCall:   funcCall,
+				ToolCalls:  toolCalls,
 				GenerationInfo: map[string]interface{}{
 					"id":                  resp.ID,
 					"model":               opts.Model,
@@ -274,5 +538,5 @@ func (l *LLM) generateContentWithClaude3(ctx context.Context, messages []llms.Me
 			},
 		},
 	}
-	return llmResponse, nil
+	return llmResponse
 }
@@ -0,0 +1,212 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// CohereCommandRequest is a request body for Cohere Command (and Command Light) models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-cohere-command.html
+type CohereCommandRequest struct {
+	Prompt         string   `json:"prompt"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	Temperature    float64  `json:"temperature,omitempty"`
+	P              float64  `json:"p,omitempty"`
+	K              int      `json:"k,omitempty"`
+	StopSequences  []string `json:"stop_sequences,omitempty"`
+	NumGenerations int      `json:"num_generations,omitempty"`
+}
+
+type CohereCommandResponse struct {
+	ID          string                            `json:"id"`
+	Prompt      string                            `json:"prompt"`
+	Generations []CohereCommandResponseGeneration `json:"generations"`
+}
+
+type CohereCommandResponseGeneration struct {
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// CohereCommandRRequest is a request body for Cohere Command R and Command R+ models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-cohere-command-r-plus.html
+type CohereCommandRRequest struct {
+	Message          string                      `json:"message"`
+	ChatHistory      []CohereCommandRChatMessage `json:"chat_history,omitempty"`
+	Preamble         string                      `json:"preamble,omitempty"`
+	Documents        []CohereDocument            `json:"documents,omitempty"`
+	MaxTokens        int                         `json:"max_tokens,omitempty"`
+	Temperature      float64                     `json:"temperature,omitempty"`
+	P                float64                     `json:"p,omitempty"`
+	K                int                         `json:"k,omitempty"`
+	StopSequences    []string                    `json:"stop_sequences,omitempty"`
+	Seed             int                         `json:"seed,omitempty"`
+	FrequencyPenalty float64                     `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64                     `json:"presence_penalty,omitempty"`
+}
+
+type CohereCommandRChatMessage struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+// CohereDocument is a document for retrieval augmented generation with Cohere Command R, e.g. {"title": "...", "snippet": "..."}.
+type CohereDocument map[string]string
+
+type CohereCommandRResponse struct {
+	ResponseID   string                      `json:"response_id"`
+	GenerationID string                      `json:"generation_id"`
+	Text         string                      `json:"text"`
+	FinishReason string                      `json:"finish_reason"`
+	ChatHistory  []CohereCommandRChatMessage `json:"chat_history"`
+	Citations    []CohereCitation            `json:"citations,omitempty"`
+}
+
+type CohereCitation struct {
+	Start       int      `json:"start"`
+	End         int      `json:"end"`
+	Text        string   `json:"text"`
+	DocumentIDs []string `json:"document_ids"`
+}
+
+type cohereDocumentsKey struct{}
+
+// ContextWithCohereDocuments returns a new context with the documents, that are sent to Cohere Command R with GenerateContent.
+func ContextWithCohereDocuments(ctx context.Context, documents []CohereDocument) context.Context {
+	return context.WithValue(ctx, cohereDocumentsKey{}, documents)
+}
+
+func cohereDocumentsFromContext(ctx context.Context) []CohereDocument {
+	documents, _ := ctx.Value(cohereDocumentsKey{}).([]CohereDocument)
+	return documents
+}
+
+// renderCohereCommandPrompt renders messages into a chat transcript for Cohere Command.
+//
+//	{system}\n\nUser: {user}\nChatbot: {assistant}\nUser: {user}\nChatbot:
+func renderCohereCommandPrompt(messages []llms.MessageContent) (string, error) {
+	return renderChatTranscript(messages, "User:", "Chatbot:")
+}
+
+// convertMessagesForCohereCommandR splits messages into the preamble, the chat history and the final human message.
+func convertMessagesForCohereCommandR(messages []llms.MessageContent) (string, []CohereCommandRChatMessage, string, error) {
+	if len(messages) == 0 {
+		return "", nil, "", errors.New("no messages")
+	}
+	last := messages[len(messages)-1]
+	if last.Role != llms.ChatMessageTypeHuman {
+		return "", nil, "", fmt.Errorf("last message must be human message, got %s", last.Role)
+	}
+	message, err := joinTextParts(last)
+	if err != nil {
+		return "", nil, "", err
+	}
+	var preamble []string
+	var chatHistory []CohereCommandRChatMessage
+	for _, m := range messages[:len(messages)-1] {
+		text, err := joinTextParts(m)
+		if err != nil {
+			return "", nil, "", err
+		}
+		switch m.Role {
+		case llms.ChatMessageTypeSystem:
+			preamble = append(preamble, text)
+		case llms.ChatMessageTypeHuman:
+			chatHistory = append(chatHistory, CohereCommandRChatMessage{Role: "USER", Message: text})
+		case llms.ChatMessageTypeAI:
+			chatHistory = append(chatHistory, CohereCommandRChatMessage{Role: "CHATBOT", Message: text})
+		default:
+			return "", nil, "", fmt.Errorf("unsupported role: %s", m.Role)
+		}
+	}
+	return strings.Join(preamble, "\n"), chatHistory, message, nil
+}
+
+func (l *LLM) generateContentWithCohereCommand(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	prompt, err := renderCohereCommandPrompt(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	payload := CohereCommandRequest{
+		Prompt:         prompt,
+		MaxTokens:      opts.MaxTokens,
+		Temperature:    opts.Temperature,
+		P:              opts.TopP,
+		K:              opts.TopK,
+		StopSequences:  opts.StopWords,
+		NumGenerations: opts.CandidateCount,
+	}
+	l.logger.Debug("generate content with cohere command", "payload", payload)
+	var resp CohereCommandResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Generations) == 0 {
+		return nil, errors.New("no generations in response")
+	}
+	choices := make([]*llms.ContentChoice, 0, len(resp.Generations))
+	for _, g := range resp.Generations {
+		choices = append(choices, &llms.ContentChoice{
+			Content:    g.Text,
+			StopReason: g.FinishReason,
+			GenerationInfo: map[string]interface{}{
+				"id":    g.ID,
+				"model": opts.Model,
+			},
+		})
+	}
+	return &llms.ContentResponse{
+		Choices: choices,
+	}, nil
+}
+
+func (l *LLM) generateContentWithCohereCommandR(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	l.applyDefaultCallOptions(opts)
+	preamble, chatHistory, message, err := convertMessagesForCohereCommandR(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+	payload := CohereCommandRRequest{
+		Message:          message,
+		ChatHistory:      chatHistory,
+		Preamble:         preamble,
+		Documents:        cohereDocumentsFromContext(ctx),
+		MaxTokens:        opts.MaxTokens,
+		Temperature:      opts.Temperature,
+		P:                opts.TopP,
+		K:                opts.TopK,
+		StopSequences:    opts.StopWords,
+		Seed:             opts.Seed,
+		FrequencyPenalty: opts.FrequencyPenalty,
+		PresencePenalty:  opts.PresencePenalty,
+	}
+	l.logger.Debug("generate content with cohere command r", "payload", payload)
+	var resp CohereCommandRResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	l.logger.Debug("generate content with cohere command r", "response_id", resp.ResponseID, "finish_reason", resp.FinishReason, "citations", len(resp.Citations))
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content:    resp.Text,
+				StopReason: resp.FinishReason,
+				GenerationInfo: map[string]interface{}{
+					"id":            resp.ResponseID,
+					"generation_id": resp.GenerationID,
+					"model":         opts.Model,
+					"citations":     resp.Citations,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+)
+
+// Input types for Cohere Embed models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-embed.html
+const (
+	CohereEmbedInputTypeSearchDocument = "search_document"
+	CohereEmbedInputTypeSearchQuery    = "search_query"
+	CohereEmbedInputTypeClassification = "classification"
+	CohereEmbedInputTypeClustering     = "clustering"
+)
+
+// cohereEmbeddingMaxTexts is the maximum number of texts per Cohere Embed request.
+const cohereEmbeddingMaxTexts = 96
+
+type cohereEmbeddingRequest struct {
+	Texts     []string `json:"texts"`
+	InputType string   `json:"input_type"`
+}
+
+type cohereEmbeddingResponse struct {
+	ID           string      `json:"id"`
+	ResponseType string      `json:"response_type"`
+	Embeddings   [][]float64 `json:"embeddings"`
+	Texts        []string    `json:"texts"`
+}
+
+type embeddingInputTypeKey struct{}
+
+// ContextWithEmbeddingInputType returns a new context with the input type, that is sent to Cohere Embed with CreateEmbedding.
+// For example, use CohereEmbedInputTypeSearchQuery when embedding a query for retrieval.
+func ContextWithEmbeddingInputType(ctx context.Context, inputType string) context.Context {
+	return context.WithValue(ctx, embeddingInputTypeKey{}, inputType)
+}
+
+func (l *LLM) embeddingInputTypeFromContext(ctx context.Context) string {
+	if inputType, ok := ctx.Value(embeddingInputTypeKey{}).(string); ok && inputType != "" {
+		return inputType
+	}
+	return l.embeddingInputType
+}
+
+func (l *LLM) createEmbeddingWithCohere(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	inputType := l.embeddingInputTypeFromContext(ctx)
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += cohereEmbeddingMaxTexts {
+		end := min(start+cohereEmbeddingMaxTexts, len(texts))
+		payload := cohereEmbeddingRequest{
+			Texts:     texts[start:end],
+			InputType: inputType,
+		}
+		l.logger.Debug("create embedding with cohere", "start", start, "end", end, "input_type", inputType)
+		var resp cohereEmbeddingResponse
+		if err := l.invokeModelWithJSON(ctx, l.embeddingModel, payload, &resp); err != nil {
+			return nil, fmt.Errorf("failed to create embedding for texts %d-%d: %w", start, end-1, err)
+		}
+		if len(resp.Embeddings) != end-start {
+			return nil, fmt.Errorf("unexpected number of embeddings: got %d, want %d", len(resp.Embeddings), end-start)
+		}
+		for _, e := range resp.Embeddings {
+			embedding := make([]float32, len(e))
+			for i, v := range e {
+				embedding[i] = float32(v)
+			}
+			embeddings = append(embeddings, embedding)
+		}
+	}
+	return &EmbeddingResponse{
+		Embeddings: embeddings,
+	}, nil
+}
@@ -0,0 +1,415 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func convertRoleForConverse(role llms.ChatMessageType) (types.ConversationRole, error) {
+	switch role {
+	case llms.ChatMessageTypeHuman, llms.ChatMessageTypeTool:
+		return types.ConversationRoleUser, nil
+	case llms.ChatMessageTypeAI:
+		return types.ConversationRoleAssistant, nil
+	default:
+		return "", fmt.Errorf("unsupported role: %s", role)
+	}
+}
+
+func convertImageFormatForConverse(mediaType string) (types.ImageFormat, error) {
+	switch mediaType {
+	case "image/png":
+		return types.ImageFormatPng, nil
+	case "image/jpeg", "image/jpg":
+		return types.ImageFormatJpeg, nil
+	case "image/gif":
+		return types.ImageFormatGif, nil
+	case "image/webp":
+		return types.ImageFormatWebp, nil
+	default:
+		return "", newError(ErrUnsupportedContent, "unsupported image media type: %s", mediaType)
+	}
+}
+
+func newImageBlockForConverse(mediaType string, data []byte) (types.ContentBlock, error) {
+	format, err := convertImageFormatForConverse(mediaType)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ContentBlockMemberImage{
+		Value: types.ImageBlock{
+			Format: format,
+			Source: &types.ImageSourceMemberBytes{Value: data},
+		},
+	}, nil
+}
+
+func convertContentPartForConverse(part llms.ContentPart) (types.ContentBlock, error) {
+	switch p := part.(type) {
+	case llms.TextContent:
+		return &types.ContentBlockMemberText{Value: p.Text}, nil
+	case llms.ImageURLContent:
+		bs, mediaType, err := fetchImageURL(p.URL)
+		if err != nil {
+			return nil, err
+		}
+		return newImageBlockForConverse(mediaType, bs)
+	case llms.BinaryContent:
+		return newImageBlockForConverse(p.MIMEType, p.Data)
+	case llms.ToolCall:
+		if p.FunctionCall == nil {
+			return nil, fmt.Errorf("tool call `%s` has no function call", p.ID)
+		}
+		var input any = map[string]any{}
+		if p.FunctionCall.Arguments != "" {
+			if err := json.Unmarshal([]byte(p.FunctionCall.Arguments), &input); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tool call arguments: %w", err)
+			}
+		}
+		return &types.ContentBlockMemberToolUse{
+			Value: types.ToolUseBlock{
+				ToolUseId: aws.String(p.ID),
+				Name:      aws.String(p.FunctionCall.Name),
+				Input:     document.NewLazyDocument(input),
+			},
+		}, nil
+	case llms.ToolCallResponse:
+		return &types.ContentBlockMemberToolResult{
+			Value: types.ToolResultBlock{
+				ToolUseId: aws.String(p.ToolCallID),
+				Content: []types.ToolResultContentBlock{
+					&types.ToolResultContentBlockMemberText{Value: p.Content},
+				},
+			},
+		}, nil
+	default:
+		return nil, newUnsupportedContentError(p)
+	}
+}
+
+// convertMessagesForConverse converts messages into the system prompts and the messages of Converse API.
+func convertMessagesForConverse(messages []llms.MessageContent) ([]types.SystemContentBlock, []types.Message, error) {
+	var system []types.SystemContentBlock
+	var result []types.Message
+	for _, message := range messages {
+		if message.Role == llms.ChatMessageTypeSystem {
+			for _, part := range message.Parts {
+				textPart, ok := part.(llms.TextContent)
+				if !ok {
+					return nil, nil, newUnsupportedContentError(part)
+				}
+				system = append(system, &types.SystemContentBlockMemberText{Value: textPart.Text})
+			}
+			continue
+		}
+		role, err := convertRoleForConverse(message.Role)
+		if err != nil {
+			return nil, nil, err
+		}
+		content := make([]types.ContentBlock, 0, len(message.Parts))
+		for _, part := range message.Parts {
+			block, err := convertContentPartForConverse(part)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = append(content, block)
+		}
+		// Converse API requires alternating user and assistant roles,
+		// so consecutive messages of the same role are merged into one.
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, content...)
+			continue
+		}
+		result = append(result, types.Message{
+			Role:    role,
+			Content: content,
+		})
+	}
+	return system, result, nil
+}
+
+func convertToolConfigForConverse(opts *llms.CallOptions) (*types.ToolConfiguration, error) {
+	tools := convertToolsForClaude3(opts)
+	if len(tools) == 0 {
+		return nil, nil
+	}
+	toolChoice, useTools, err := convertToolChoiceForClaude3(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !useTools {
+		return nil, nil
+	}
+	cfg := &types.ToolConfiguration{}
+	for _, tool := range tools {
+		spec := types.ToolSpecification{
+			Name:        aws.String(tool.Name),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.InputSchema)},
+		}
+		if tool.Description != "" {
+			spec.Description = aws.String(tool.Description)
+		}
+		cfg.Tools = append(cfg.Tools, &types.ToolMemberToolSpec{Value: spec})
+	}
+	if toolChoice != nil {
+		switch toolChoice.Type {
+		case "auto":
+			cfg.ToolChoice = &types.ToolChoiceMemberAuto{}
+		case "any":
+			cfg.ToolChoice = &types.ToolChoiceMemberAny{}
+		case "tool":
+			cfg.ToolChoice = &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String(toolChoice.Name)}}
+		}
+	}
+	return cfg, nil
+}
+
+// converseTopKFields are the names of the top_k field in additionalModelRequestFields of the model families that accept it.
+// The Converse API has no common inference parameter for top_k.
+var converseTopKFields = map[ModelFamily]string{
+	ModelFamilyClaude2:        "top_k",
+	ModelFamilyClaude3:        "top_k",
+	ModelFamilyMistral:        "top_k",
+	ModelFamilyCohereCommandR: "k",
+}
+
+// additionalModelRequestFieldsForConverse returns the model specific fields of the call options, or nil if there are none.
+func additionalModelRequestFieldsForConverse(family ModelFamily, opts *llms.CallOptions) document.Interface {
+	field, ok := converseTopKFields[family]
+	if !ok || opts.TopK <= 0 {
+		return nil
+	}
+	return document.NewLazyDocument(map[string]any{field: opts.TopK})
+}
+
+// applyDefaultCallOptionsForConverse fills unset call options with the LLM defaults.
+// Chat models end their turn by themselves on the Converse API,
+// so the default stop word `Human:` of the text completion models is sent only if set by the caller.
+func (l *LLM) applyDefaultCallOptionsForConverse(opts *llms.CallOptions) {
+	hasStopWords := opts.StopWords != nil || l.stopWords != nil
+	l.applyDefaultCallOptions(opts)
+	if !hasStopWords {
+		opts.StopWords = nil
+	}
+}
+
+func (l *LLM) generateContentWithConverse(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	l.applyDefaultCallOptionsForConverse(opts)
+	system, msgs, err := convertMessagesForConverse(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, errors.New("no messages")
+	}
+	toolConfig, err := convertToolConfigForConverse(opts)
+	if err != nil {
+		return nil, err
+	}
+	inferenceConfig := &types.InferenceConfiguration{
+		MaxTokens:     aws.Int32(int32(opts.MaxTokens)),
+		Temperature:   aws.Float32(float32(opts.Temperature)),
+		TopP:          aws.Float32(float32(opts.TopP)),
+		StopSequences: opts.StopWords,
+	}
+	additionalFields := additionalModelRequestFieldsForConverse(l.lookupModelFamily(opts.Model), opts)
+	l.logger.Debug("generate content with converse", "model", opts.Model, "messages_count", len(msgs), "max_tokens", opts.MaxTokens, "temperature", opts.Temperature, "top_p", opts.TopP, "top_k", opts.TopK, "stop_sequences", opts.StopWords)
+	if opts.StreamingFunc != nil {
+		return l.generateContentWithConverseStream(ctx, &bedrockruntime.ConverseStreamInput{
+			ModelId:                      aws.String(opts.Model),
+			System:                       system,
+			Messages:                     msgs,
+			InferenceConfig:              inferenceConfig,
+			ToolConfig:                   toolConfig,
+			AdditionalModelRequestFields: additionalFields,
+		}, opts)
+	}
+	client, err := asOptionalClient[BedrockConverseClient](l.client, "Converse")
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:                      aws.String(opts.Model),
+		System:                       system,
+		Messages:                     msgs,
+		InferenceConfig:              inferenceConfig,
+		ToolConfig:                   toolConfig,
+		AdditionalModelRequestFields: additionalFields,
+	})
+	if err != nil {
+		return nil, wrapAPIError("failed to converse", err)
+	}
+	msg, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected converse output type: %T", output.Output)
+	}
+	var content strings.Builder
+	var toolCalls []llms.ToolCall
+	for _, block := range msg.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			content.WriteString(b.Value)
+		case *types.ContentBlockMemberToolUse:
+			arguments := "{}"
+			if b.Value.Input != nil {
+				bs, err := b.Value.Input.MarshalSmithyDocument()
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal tool use input: %w", err)
+				}
+				arguments = string(bs)
+			}
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:   aws.ToString(b.Value.ToolUseId),
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name:      aws.ToString(b.Value.Name),
+					Arguments: arguments,
+				},
+			})
+		default:
+			l.logger.Debug("unknown converse content block", "type", fmt.Sprintf("%T", b))
+		}
+	}
+	l.logger.Debug("generate content with converse", "stop_reason", output.StopReason)
+	return newContentResponseFromConverse(content.String(), toolCalls, output.StopReason, output.Usage, opts), nil
+}
+
+func (l *LLM) generateContentWithConverseStream(ctx context.Context, input *bedrockruntime.ConverseStreamInput, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	client, err := asOptionalClient[BedrockConverseStreamClient](l.client, "ConverseStream")
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.ConverseStream(ctx, input)
+	if err != nil {
+		return nil, wrapAPIError("failed to converse stream", err)
+	}
+	stream := output.GetStream()
+	defer stream.Close()
+	acc := newConverseStreamAccumulator()
+	for event := range stream.Events() {
+		text := acc.add(event)
+		if text == "" {
+			continue
+		}
+		if err := opts.StreamingFunc(ctx, []byte(text)); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, wrapAPIError("failed to read converse stream", err)
+	}
+	l.logger.Debug("generate content stream with converse", "stop_reason", acc.stopReason)
+	return newContentResponseFromConverse(acc.content.String(), acc.toolCalls, acc.stopReason, acc.usage, opts), nil
+}
+
+// converseStreamAccumulator builds the content, tool calls, stop reason and usage from the events of the converse stream.
+type converseStreamAccumulator struct {
+	content           strings.Builder
+	stopReason        types.StopReason
+	usage             *types.TokenUsage
+	toolCalls         []llms.ToolCall
+	toolCallIndexes   map[int32]int
+	toolCallArguments map[int32]*strings.Builder
+}
+
+func newConverseStreamAccumulator() *converseStreamAccumulator {
+	return &converseStreamAccumulator{
+		toolCallIndexes:   make(map[int32]int),
+		toolCallArguments: make(map[int32]*strings.Builder),
+	}
+}
+
+// add applies the event to the accumulated response, and returns the text delta to stream if any.
+func (a *converseStreamAccumulator) add(event types.ConverseStreamOutput) string {
+	switch e := event.(type) {
+	case *types.ConverseStreamOutputMemberContentBlockStart:
+		a.startContentBlock(e.Value)
+	case *types.ConverseStreamOutputMemberContentBlockDelta:
+		return a.addContentBlockDelta(e.Value)
+	case *types.ConverseStreamOutputMemberContentBlockStop:
+		a.stopContentBlock(e.Value)
+	case *types.ConverseStreamOutputMemberMessageStop:
+		a.stopReason = e.Value.StopReason
+	case *types.ConverseStreamOutputMemberMetadata:
+		a.usage = e.Value.Usage
+	}
+	return ""
+}
+
+func (a *converseStreamAccumulator) startContentBlock(event types.ContentBlockStartEvent) {
+	start, ok := event.Start.(*types.ContentBlockStartMemberToolUse)
+	if !ok {
+		return
+	}
+	index := aws.ToInt32(event.ContentBlockIndex)
+	a.toolCallIndexes[index] = len(a.toolCalls)
+	a.toolCallArguments[index] = &strings.Builder{}
+	a.toolCalls = append(a.toolCalls, llms.ToolCall{
+		ID:   aws.ToString(start.Value.ToolUseId),
+		Type: "function",
+		FunctionCall: &llms.FunctionCall{
+			Name: aws.ToString(start.Value.Name),
+		},
+	})
+}
+
+func (a *converseStreamAccumulator) addContentBlockDelta(event types.ContentBlockDeltaEvent) string {
+	switch d := event.Delta.(type) {
+	case *types.ContentBlockDeltaMemberText:
+		a.content.WriteString(d.Value)
+		return d.Value
+	case *types.ContentBlockDeltaMemberToolUse:
+		if b, ok := a.toolCallArguments[aws.ToInt32(event.ContentBlockIndex)]; ok {
+			b.WriteString(aws.ToString(d.Value.Input))
+		}
+	}
+	return ""
+}
+
+func (a *converseStreamAccumulator) stopContentBlock(event types.ContentBlockStopEvent) {
+	index := aws.ToInt32(event.ContentBlockIndex)
+	b, ok := a.toolCallArguments[index]
+	if !ok {
+		return
+	}
+	arguments := b.String()
+	if arguments == "" {
+		arguments = "{}"
+	}
+	a.toolCalls[a.toolCallIndexes[index]].FunctionCall.Arguments = arguments
+}
+
+func newContentResponseFromConverse(content string, toolCalls []llms.ToolCall, stopReason types.StopReason, usage *types.TokenUsage, opts *llms.CallOptions) *llms.ContentResponse {
+	generationInfo := map[string]interface{}{
+		"model": opts.Model,
+	}
+	if usage != nil {
+		generationInfo["usage.input_tokens"] = int(aws.ToInt32(usage.InputTokens))
+		generationInfo["usage.output_tokens"] = int(aws.ToInt32(usage.OutputTokens))
+		generationInfo["usage.total_tokens"] = int(aws.ToInt32(usage.TotalTokens))
+	}
+	var funcCall *llms.FunctionCall
+	if len(toolCalls) > 0 {
+		funcCall = toolCalls[0].FunctionCall
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content:        content,
+				StopReason:     string(stopReason),
+				GenerationInfo: generationInfo,
+				FuncCall:       funcCall,
+				ToolCalls:      toolCalls,
+			},
+		},
+	}
+}
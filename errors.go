@@ -0,0 +1,113 @@
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// Sentinel errors to classify failures with errors.Is.
+var (
+	ErrUnsupportedModel      = errors.New("unsupported model")
+	ErrUnsupportedContent    = errors.New("unsupported content part")
+	ErrThrottled             = errors.New("throttled")
+	ErrContextWindowExceeded = errors.New("context window exceeded")
+	ErrContentFiltered       = errors.New("content filtered")
+	ErrValidation            = errors.New("validation error")
+	ErrMaxTokensReached      = errors.New("max tokens reached")
+	ErrCircuitOpen           = errors.New("circuit breaker is open")
+)
+
+// Error is an error classified by Kind, one of the sentinel errors.
+// It matches Kind with errors.Is, and unwraps to the underlying error (e.g. smithy.APIError) if any.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind error, format string, args ...any) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func newUnsupportedModelError(modelType string, modelID string) *Error {
+	if modelType == "" {
+		return newError(ErrUnsupportedModel, "model `%s` not supported", modelID)
+	}
+	return newError(ErrUnsupportedModel, "%s model `%s` not supported", modelType, modelID)
+}
+
+func newUnsupportedContentError(part any) *Error {
+	return newError(ErrUnsupportedContent, "unsupported content type: %T", part)
+}
+
+// contextWindowExceededMessages are substrings of ValidationException messages returned when the input is too long.
+var contextWindowExceededMessages = []string{
+	"too long",
+	"too many input tokens",
+	"maximum context length",
+	"context window",
+}
+
+// wrapAPIError wraps err returned by the Bedrock Runtime API with message, classifying it by the API error code.
+func wrapAPIError(message string, err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	var kind error
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "ServiceQuotaExceededException", "TooManyRequestsException":
+		kind = ErrThrottled
+	case "ValidationException":
+		kind = ErrValidation
+		lower := strings.ToLower(apiErr.ErrorMessage())
+		for _, m := range contextWindowExceededMessages {
+			if strings.Contains(lower, m) {
+				kind = ErrContextWindowExceeded
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// StopReasonError returns ErrMaxTokensReached or ErrContentFiltered wrapped in *Error
+// if the stop reason of llms.ContentChoice indicates that the generation was truncated or filtered.
+// It returns nil for the other stop reasons.
+func StopReasonError(stopReason string) error {
+	switch strings.ToLower(stopReason) {
+	case "max_tokens", "length", "max_tokens_reached":
+		return newError(ErrMaxTokensReached, "generation stopped: %s", stopReason)
+	case "content_filtered", "guardrail_intervened", "error_toxic":
+		return newError(ErrContentFiltered, "generation stopped: %s", stopReason)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+type regionClient struct {
+	region string
+	client BedrockClient
+}
+
+// servedRegion records the region that served a call, set by failoverClient.
+type servedRegion struct {
+	mu     sync.Mutex
+	region string
+}
+
+type servedRegionKey struct{}
+
+func contextWithServedRegion(ctx context.Context) (context.Context, *servedRegion) {
+	r := &servedRegion{}
+	return context.WithValue(ctx, servedRegionKey{}, r), r
+}
+
+func (r *servedRegion) set(region string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.region = region
+}
+
+func (r *servedRegion) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.region
+}
+
+// isFailoverError reports whether the call should be retried in the next region:
+// throttling, server errors, open circuits, network errors and models that are not available in the region.
+// Errors of the request itself and cancellation by the caller are not retried.
+func isFailoverError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return isNetworkError(err)
+	}
+	if apiErr.ErrorFault() == smithy.FaultServer {
+		return true
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "ServiceUnavailableException", "InternalServerException",
+		"ModelNotReadyException", "ModelTimeoutException", "ResourceNotFoundException", "AccessDeniedException":
+		return true
+	case "ValidationException":
+		return strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "model identifier")
+	default:
+		return false
+	}
+}
+
+// isNetworkError reports whether err is a failure to send the request or to receive the response.
+func isNetworkError(err error) bool {
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// failoverClient is a BedrockClient that calls the clients of the regions in order until one succeeds.
+// Streaming calls fail over only when opening the stream fails.
+type failoverClient struct {
+	clients []regionClient
+	logger  *slog.Logger
+}
+
+var (
+	_ BedrockClient               = (*failoverClient)(nil)
+	_ BedrockStreamClient         = (*failoverClient)(nil)
+	_ BedrockConverseClient       = (*failoverClient)(nil)
+	_ BedrockConverseStreamClient = (*failoverClient)(nil)
+)
+
+func newFailoverClient(clients []regionClient, logger *slog.Logger) *failoverClient {
+	return &failoverClient{
+		clients: clients,
+		logger:  logger,
+	}
+}
+
+func withFailover[In, Out any](ctx context.Context, c *failoverClient, newOperation func(BedrockClient) operation[In, Out], modelID *string, params In, optFns []func(*bedrockruntime.Options)) (Out, error) {
+	var output Out
+	var err error
+	for i, rc := range c.clients {
+		op := newOperation(rc.client)
+		if op.err != nil {
+			var zero Out
+			return zero, op.err
+		}
+		output, err = op.call(ctx, params, optFns...)
+		if err == nil {
+			if r, ok := ctx.Value(servedRegionKey{}).(*servedRegion); ok {
+				r.set(rc.region)
+			}
+			return output, nil
+		}
+		if i == len(c.clients)-1 || !isFailoverError(err) {
+			break
+		}
+		c.logger.Warn("failing over to the next region",
+			"operation", op.name,
+			"model", aws.ToString(modelID),
+			"region", rc.region,
+			"next_region", c.clients[i+1].region,
+			"err", err,
+		)
+	}
+	return output, err
+}
+
+func (c *failoverClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	return withFailover(ctx, c, invokeModelOperation, params.ModelId, params, optFns)
+}
+
+func (c *failoverClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return withFailover(ctx, c, invokeModelWithResponseStreamOperation, params.ModelId, params, optFns)
+}
+
+func (c *failoverClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	return withFailover(ctx, c, converseOperation, params.ModelId, params, optFns)
+}
+
+func (c *failoverClient) ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error) {
+	return withFailover(ctx, c, converseStreamOperation, params.ModelId, params, optFns)
+}
@@ -0,0 +1,66 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aws/smithy-go"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// isFallbackError reports whether GenerateContent should try the next fallback model:
+// throttling, context window exceeded, open circuits and availability errors.
+func isFallbackError(err error) bool {
+	if errors.Is(err, ErrThrottled) || errors.Is(err, ErrContextWindowExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ServiceUnavailableException", "InternalServerException", "ModelNotReadyException",
+		"ModelTimeoutException", "ResourceNotFoundException", "AccessDeniedException":
+		return true
+	default:
+		return false
+	}
+}
+
+// generateContentWithFallback generates content with opts.Model, and then the fallback models in order
+// while it fails with a fallback error. It does not fall back once a streaming chunk has been sent.
+func (l *LLM) generateContentWithFallback(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	models := []string{opts.Model}
+	for _, model := range l.fallbackModels {
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	var streamed bool
+	if streamingFunc := opts.StreamingFunc; streamingFunc != nil {
+		opts.StreamingFunc = func(ctx context.Context, chunk []byte) error {
+			streamed = true
+			return streamingFunc(ctx, chunk)
+		}
+	}
+	var err error
+	for i, model := range models {
+		callOpts := *opts
+		callOpts.Model = model
+		var resp *llms.ContentResponse
+		if l.useConverseAPI {
+			resp, err = l.generateContentWithConverse(ctx, messages, &callOpts)
+		} else {
+			resp, err = l.generateContentWithModel(ctx, messages, &callOpts)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if streamed || i == len(models)-1 || !isFallbackError(err) {
+			break
+		}
+		l.logger.Warn("falling back to the next model", "model", model, "next_model", models[i+1], "err", err)
+	}
+	return nil, err
+}
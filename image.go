@@ -0,0 +1,24 @@
+package bedrock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// fetchImageURL downloads the image from the given URL and returns its body and detected media type.
+func fetchImageURL(url string) ([]byte, string, error) {
+	resp, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusNoContent {
+		return nil, "", fmt.Errorf("failed to get image: %s", resp.Status)
+	}
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	return bs, http.DetectContentType(bs), nil
+}
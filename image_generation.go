@@ -0,0 +1,338 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Task types for Titan Image Generator.
+const (
+	ImageTaskTypeTextToImage    = "TEXT_IMAGE"
+	ImageTaskTypeInpainting     = "INPAINTING"
+	ImageTaskTypeOutpainting    = "OUTPAINTING"
+	ImageTaskTypeImageVariation = "IMAGE_VARIATION"
+)
+
+// ImageGenerationInput is the input of GenerateImage.
+// Fields that are not supported by the image model are ignored.
+type ImageGenerationInput struct {
+	// TaskType is the Titan Image Generator task type. Default is ImageTaskTypeTextToImage.
+	// Stable Diffusion XL supports text to image only.
+	TaskType       string
+	Prompt         string
+	NegativePrompt string
+	// InputImages are the source images for inpainting, outpainting (the first one is used) and image variation.
+	InputImages [][]byte
+	// MaskImage or MaskPrompt specifies the area to be edited for inpainting and outpainting.
+	MaskImage  []byte
+	MaskPrompt string
+	// OutpaintingMode is DEFAULT or PRECISE.
+	OutpaintingMode    string
+	SimilarityStrength float64
+	NumberOfImages     int
+	Width              int
+	Height             int
+	CFGScale           float64
+	Seed               int
+	// Quality is standard or premium. Titan Image Generator only.
+	Quality string
+	// Steps is the number of diffusion steps. Stable Diffusion XL only.
+	Steps int
+	// StylePreset is e.g. photographic, digital-art, anime. Stable Diffusion XL only.
+	StylePreset string
+}
+
+// ImageGenerationOutput is the output of GenerateImage.
+type ImageGenerationOutput struct {
+	Images []*GeneratedImage
+}
+
+type GeneratedImage struct {
+	// Data is the decoded image (PNG).
+	Data []byte
+	// Seed and FinishReason are reported by Stable Diffusion XL only, and empty for Titan Image Generator.
+	Seed         int
+	FinishReason string
+}
+
+// ImageGenerationHandler is an optional interface of LLM.CallbacksHandler to be notified of GenerateImage calls.
+// One of HandleImageGenerationEnd and HandleImageGenerationError is called after HandleImageGenerationStart.
+type ImageGenerationHandler interface {
+	HandleImageGenerationStart(ctx context.Context, input *ImageGenerationInput)
+	HandleImageGenerationEnd(ctx context.Context, output *ImageGenerationOutput)
+	HandleImageGenerationError(ctx context.Context, err error)
+}
+
+// StableDiffusionXLRequest is a request body for Stability AI Stable Diffusion XL.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-diffusion-1-0-text-image.html
+type StableDiffusionXLRequest struct {
+	TextPrompts []StableDiffusionXLTextPrompt `json:"text_prompts"`
+	Height      int                           `json:"height,omitempty"`
+	Width       int                           `json:"width,omitempty"`
+	CFGScale    float64                       `json:"cfg_scale,omitempty"`
+	Seed        int                           `json:"seed,omitempty"`
+	Steps       int                           `json:"steps,omitempty"`
+	Samples     int                           `json:"samples,omitempty"`
+	StylePreset string                        `json:"style_preset,omitempty"`
+}
+
+type StableDiffusionXLTextPrompt struct {
+	Text   string  `json:"text"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+type StableDiffusionXLResponse struct {
+	Result    string                      `json:"result"`
+	Artifacts []StableDiffusionXLArtifact `json:"artifacts"`
+}
+
+type StableDiffusionXLArtifact struct {
+	Seed         int    `json:"seed"`
+	Base64       string `json:"base64"`
+	FinishReason string `json:"finishReason"`
+}
+
+// TitanImageRequest is a request body for Amazon Titan Image Generator.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-image.html
+type TitanImageRequest struct {
+	TaskType              string                          `json:"taskType"`
+	TextToImageParams     *TitanImageTextToImageParams    `json:"textToImageParams,omitempty"`
+	InPaintingParams      *TitanImageInPaintingParams     `json:"inPaintingParams,omitempty"`
+	OutPaintingParams     *TitanImageOutPaintingParams    `json:"outPaintingParams,omitempty"`
+	ImageVariationParams  *TitanImageImageVariationParams `json:"imageVariationParams,omitempty"`
+	ImageGenerationConfig *TitanImageGenerationConfig     `json:"imageGenerationConfig,omitempty"`
+}
+
+type TitanImageTextToImageParams struct {
+	Text         string `json:"text"`
+	NegativeText string `json:"negativeText,omitempty"`
+}
+
+type TitanImageInPaintingParams struct {
+	Image        string `json:"image"`
+	Text         string `json:"text,omitempty"`
+	NegativeText string `json:"negativeText,omitempty"`
+	MaskPrompt   string `json:"maskPrompt,omitempty"`
+	MaskImage    string `json:"maskImage,omitempty"`
+}
+
+type TitanImageOutPaintingParams struct {
+	Image           string `json:"image"`
+	Text            string `json:"text,omitempty"`
+	NegativeText    string `json:"negativeText,omitempty"`
+	MaskPrompt      string `json:"maskPrompt,omitempty"`
+	MaskImage       string `json:"maskImage,omitempty"`
+	OutPaintingMode string `json:"outPaintingMode,omitempty"`
+}
+
+type TitanImageImageVariationParams struct {
+	Images             []string `json:"images"`
+	Text               string   `json:"text,omitempty"`
+	NegativeText       string   `json:"negativeText,omitempty"`
+	SimilarityStrength float64  `json:"similarityStrength,omitempty"`
+}
+
+type TitanImageGenerationConfig struct {
+	NumberOfImages int     `json:"numberOfImages,omitempty"`
+	Height         int     `json:"height,omitempty"`
+	Width          int     `json:"width,omitempty"`
+	CFGScale       float64 `json:"cfgScale,omitempty"`
+	Seed           int     `json:"seed,omitempty"`
+	Quality        string  `json:"quality,omitempty"`
+}
+
+type TitanImageResponse struct {
+	Images []string `json:"images"`
+	Error  string   `json:"error"`
+}
+
+// GenerateImage generates images with the image model configured by WithImageModel.
+// The call is reported to LLM.CallbacksHandler if it implements ImageGenerationHandler.
+func (l *LLM) GenerateImage(ctx context.Context, input *ImageGenerationInput) (*ImageGenerationOutput, error) {
+	l.logger.Debug("bedrock.LLM.GenerateImage called", "model", l.imageModel)
+	if input == nil {
+		return nil, newError(ErrValidation, "no image generation input")
+	}
+	handler, _ := l.CallbacksHandler.(ImageGenerationHandler)
+	if handler != nil {
+		handler.HandleImageGenerationStart(ctx, input)
+	}
+	var output *ImageGenerationOutput
+	var err error
+	switch l.lookupModelFamily(l.imageModel) {
+	case ModelFamilyStableDiffusionXL:
+		output, err = l.generateImageWithStableDiffusionXL(ctx, input)
+	case ModelFamilyTitanImageGenerator:
+		output, err = l.generateImageWithTitan(ctx, input)
+	default:
+		err = newUnsupportedModelError("image", l.imageModel)
+	}
+	if err != nil {
+		if handler != nil {
+			handler.HandleImageGenerationError(ctx, err)
+		}
+		return nil, err
+	}
+	if handler != nil {
+		handler.HandleImageGenerationEnd(ctx, output)
+	}
+	return output, nil
+}
+
+func (l *LLM) generateImageWithStableDiffusionXL(ctx context.Context, input *ImageGenerationInput) (*ImageGenerationOutput, error) {
+	if input.TaskType != "" && input.TaskType != ImageTaskTypeTextToImage {
+		return nil, fmt.Errorf("task type `%s` not supported by %s", input.TaskType, l.imageModel)
+	}
+	if input.Prompt == "" {
+		return nil, errors.New("no prompt")
+	}
+	prompts := []StableDiffusionXLTextPrompt{
+		{Text: input.Prompt, Weight: 1},
+	}
+	if input.NegativePrompt != "" {
+		prompts = append(prompts, StableDiffusionXLTextPrompt{Text: input.NegativePrompt, Weight: -1})
+	}
+	payload := StableDiffusionXLRequest{
+		TextPrompts: prompts,
+		Height:      input.Height,
+		Width:       input.Width,
+		CFGScale:    input.CFGScale,
+		Seed:        input.Seed,
+		Steps:       input.Steps,
+		Samples:     input.NumberOfImages,
+		StylePreset: input.StylePreset,
+	}
+	l.logger.Debug("generate image with stable diffusion xl", "payload", payload)
+	var resp StableDiffusionXLResponse
+	if err := l.invokeModelWithJSON(ctx, l.imageModel, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Artifacts) == 0 {
+		return nil, fmt.Errorf("no artifacts in response: result=%s", resp.Result)
+	}
+	output := &ImageGenerationOutput{
+		Images: make([]*GeneratedImage, 0, len(resp.Artifacts)),
+	}
+	for _, a := range resp.Artifacts {
+		data, err := base64.StdEncoding.DecodeString(a.Base64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		output.Images = append(output.Images, &GeneratedImage{
+			Data:         data,
+			Seed:         a.Seed,
+			FinishReason: a.FinishReason,
+		})
+	}
+	return output, nil
+}
+
+func encodeImagesToBase64(images [][]byte) []string {
+	encoded := make([]string, 0, len(images))
+	for _, image := range images {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(image))
+	}
+	return encoded
+}
+
+func newTitanImageRequest(input *ImageGenerationInput) (*TitanImageRequest, error) {
+	taskType := input.TaskType
+	if taskType == "" {
+		taskType = ImageTaskTypeTextToImage
+	}
+	payload := &TitanImageRequest{
+		TaskType: taskType,
+		ImageGenerationConfig: &TitanImageGenerationConfig{
+			NumberOfImages: input.NumberOfImages,
+			Height:         input.Height,
+			Width:          input.Width,
+			CFGScale:       input.CFGScale,
+			Seed:           input.Seed,
+			Quality:        input.Quality,
+		},
+	}
+	images := encodeImagesToBase64(input.InputImages)
+	var maskImage string
+	if input.MaskImage != nil {
+		maskImage = base64.StdEncoding.EncodeToString(input.MaskImage)
+	}
+	switch taskType {
+	case ImageTaskTypeTextToImage:
+		if input.Prompt == "" {
+			return nil, errors.New("no prompt")
+		}
+		payload.TextToImageParams = &TitanImageTextToImageParams{
+			Text:         input.Prompt,
+			NegativeText: input.NegativePrompt,
+		}
+	case ImageTaskTypeInpainting:
+		if len(images) == 0 {
+			return nil, errors.New("no input image")
+		}
+		payload.InPaintingParams = &TitanImageInPaintingParams{
+			Image:        images[0],
+			Text:         input.Prompt,
+			NegativeText: input.NegativePrompt,
+			MaskPrompt:   input.MaskPrompt,
+			MaskImage:    maskImage,
+		}
+	case ImageTaskTypeOutpainting:
+		if len(images) == 0 {
+			return nil, errors.New("no input image")
+		}
+		payload.OutPaintingParams = &TitanImageOutPaintingParams{
+			Image:           images[0],
+			Text:            input.Prompt,
+			NegativeText:    input.NegativePrompt,
+			MaskPrompt:      input.MaskPrompt,
+			MaskImage:       maskImage,
+			OutPaintingMode: input.OutpaintingMode,
+		}
+	case ImageTaskTypeImageVariation:
+		if len(images) == 0 {
+			return nil, errors.New("no input image")
+		}
+		payload.ImageVariationParams = &TitanImageImageVariationParams{
+			Images:             images,
+			Text:               input.Prompt,
+			NegativeText:       input.NegativePrompt,
+			SimilarityStrength: input.SimilarityStrength,
+		}
+	default:
+		return nil, fmt.Errorf("task type `%s` not supported", taskType)
+	}
+	return payload, nil
+}
+
+func (l *LLM) generateImageWithTitan(ctx context.Context, input *ImageGenerationInput) (*ImageGenerationOutput, error) {
+	payload, err := newTitanImageRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	l.logger.Debug("generate image with titan", "task_type", payload.TaskType)
+	var resp TitanImageResponse
+	if err := l.invokeModelWithJSON(ctx, l.imageModel, payload, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("failed to generate image: %s", resp.Error)
+	}
+	if len(resp.Images) == 0 {
+		return nil, errors.New("no images in response")
+	}
+	output := &ImageGenerationOutput{
+		Images: make([]*GeneratedImage, 0, len(resp.Images)),
+	}
+	for _, image := range resp.Images {
+		data, err := base64.StdEncoding.DecodeString(image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		output.Images = append(output.Images, &GeneratedImage{
+			Data: data,
+		})
+	}
+	return output, nil
+}
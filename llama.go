@@ -0,0 +1,133 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// LlamaRequest is a request body for Meta Llama 2 / Llama 3 models.
+// Llama models do not accept stop sequences and top_k, so llms.WithStopWords and llms.WithTopK are not sent.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-meta.html
+type LlamaRequest struct {
+	Prompt      string  `json:"prompt"`
+	MaxGenLen   int     `json:"max_gen_len,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	TopP        float64 `json:"top_p,omitempty"`
+}
+
+type LlamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int    `json:"prompt_token_count"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+// renderLlama2Prompt renders messages into Llama 2 chat template.
+//
+//	<s>[INST] <<SYS>>\n{system}\n<</SYS>>\n\n{user} [/INST] {assistant} </s><s>[INST] {user} [/INST]
+func renderLlama2Prompt(messages []llms.MessageContent) (string, error) {
+	var builder strings.Builder
+	var system string
+	inInstruction := false
+	for _, message := range messages {
+		text, err := joinTextParts(message)
+		if err != nil {
+			return "", err
+		}
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			system = text
+		case llms.ChatMessageTypeHuman:
+			if inInstruction {
+				return "", errors.New("consecutive human messages are not supported")
+			}
+			builder.WriteString("<s>[INST] ")
+			if system != "" {
+				builder.WriteString("<<SYS>>\n" + system + "\n<</SYS>>\n\n")
+				system = ""
+			}
+			builder.WriteString(strings.TrimSpace(text))
+			builder.WriteString(" [/INST]")
+			inInstruction = true
+		case llms.ChatMessageTypeAI:
+			if !inInstruction {
+				return "", errors.New("ai message must follow human message")
+			}
+			builder.WriteString(" " + strings.TrimSpace(text) + " </s>")
+			inInstruction = false
+		default:
+			return "", fmt.Errorf("unsupported role: %s", message.Role)
+		}
+	}
+	return builder.String(), nil
+}
+
+// renderLlama3Prompt renders messages into Llama 3 instruct template.
+//
+//	<|begin_of_text|><|start_header_id|>{role}<|end_header_id|>\n\n{content}<|eot_id|>...<|start_header_id|>assistant<|end_header_id|>\n\n
+func renderLlama3Prompt(messages []llms.MessageContent) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("<|begin_of_text|>")
+	for _, message := range messages {
+		text, err := joinTextParts(message)
+		if err != nil {
+			return "", err
+		}
+		var role string
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			role = "system"
+		case llms.ChatMessageTypeHuman:
+			role = "user"
+		case llms.ChatMessageTypeAI:
+			role = "assistant"
+		default:
+			return "", fmt.Errorf("unsupported role: %s", message.Role)
+		}
+		builder.WriteString("<|start_header_id|>" + role + "<|end_header_id|>\n\n")
+		builder.WriteString(strings.TrimSpace(text))
+		builder.WriteString("<|eot_id|>")
+	}
+	builder.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	return builder.String(), nil
+}
+
+func (l *LLM) generateContentWithLlama(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions, render func([]llms.MessageContent) (string, error)) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	prompt, err := render(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	payload := LlamaRequest{
+		Prompt:      prompt,
+		MaxGenLen:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+	}
+	l.logger.Debug("generate content with llama", "payload", payload)
+	var resp LlamaResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	l.logger.Debug("generate content with llama", "stop_reason", resp.StopReason, "prompt_token_count", resp.PromptTokenCount, "generation_token_count", resp.GenerationTokenCount)
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content:    resp.Generation,
+				StopReason: resp.StopReason,
+				GenerationInfo: map[string]interface{}{
+					"model":               opts.Model,
+					"usage.input_tokens":  resp.PromptTokenCount,
+					"usage.output_tokens": resp.GenerationTokenCount,
+				},
+			},
+		},
+	}, nil
+}
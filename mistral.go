@@ -0,0 +1,112 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// MistralRequest is a request body for Mistral AI models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-mistral.html
+type MistralRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	TopK        int      `json:"top_k,omitempty"`
+}
+
+type MistralResponse struct {
+	Outputs []MistralResponseOutput `json:"outputs"`
+}
+
+type MistralResponseOutput struct {
+	Text       string `json:"text"`
+	StopReason string `json:"stop_reason"`
+}
+
+// renderMistralPrompt renders messages into Mistral instruct template.
+// Mistral has no system role, so the system message is prepended to the first instruction.
+//
+//	<s>[INST] {system}\n\n{user} [/INST]{assistant}</s>[INST] {user} [/INST]
+func renderMistralPrompt(messages []llms.MessageContent) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("<s>")
+	var system string
+	inInstruction := false
+	for _, message := range messages {
+		text, err := joinTextParts(message)
+		if err != nil {
+			return "", err
+		}
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			system = strings.TrimSpace(text)
+		case llms.ChatMessageTypeHuman:
+			if inInstruction {
+				return "", errors.New("consecutive human messages are not supported")
+			}
+			builder.WriteString("[INST] ")
+			if system != "" {
+				builder.WriteString(system + "\n\n")
+				system = ""
+			}
+			builder.WriteString(strings.TrimSpace(text))
+			builder.WriteString(" [/INST]")
+			inInstruction = true
+		case llms.ChatMessageTypeAI:
+			if !inInstruction {
+				return "", errors.New("ai message must follow human message")
+			}
+			builder.WriteString(strings.TrimSpace(text) + "</s>")
+			inInstruction = false
+		default:
+			return "", fmt.Errorf("unsupported role: %s", message.Role)
+		}
+	}
+	return builder.String(), nil
+}
+
+func (l *LLM) generateContentWithMistral(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	prompt, err := renderMistralPrompt(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	payload := MistralRequest{
+		Prompt:      prompt,
+		MaxTokens:   opts.MaxTokens,
+		Stop:        opts.StopWords,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		TopK:        opts.TopK,
+	}
+	l.logger.Debug("generate content with mistral", "payload", payload)
+	var resp MistralResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Outputs) == 0 {
+		return nil, errors.New("no outputs in response")
+	}
+	choices := make([]*llms.ContentChoice, 0, len(resp.Outputs))
+	for _, o := range resp.Outputs {
+		choices = append(choices, &llms.ContentChoice{
+			Content:    o.Text,
+			StopReason: o.StopReason,
+			GenerationInfo: map[string]interface{}{
+				"model": opts.Model,
+			},
+		})
+	}
+	return &llms.ContentResponse{
+		Choices: choices,
+	}, nil
+}
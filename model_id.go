@@ -2,9 +2,44 @@ package bedrock
 
 // https://docs.aws.amazon.com/bedrock/latest/userguide/model-ids-arns.html
 const (
-	TitanEmbeddingG1Text = "amazon.titan-embed-text-v1"
-	Claude2              = "anthropic.claude-v2:1"
-	ClaudeInstant        = "anthropic.claude-instant-v1"
-	Claude3Sonnet        = "anthropic.claude-3-sonnet-20240229-v1:0"
-	Claude3Haiku         = "anthropic.claude-3-haiku-20240307-v1:0"
+	TitanEmbeddingG1Text      = "amazon.titan-embed-text-v1"
+	TitanEmbedTextV2          = "amazon.titan-embed-text-v2:0"
+	TitanEmbedImageV1         = "amazon.titan-embed-image-v1"
+	CohereEmbedEnglishV3      = "cohere.embed-english-v3"
+	CohereEmbedMultilingualV3 = "cohere.embed-multilingual-v3"
+	Claude2                   = "anthropic.claude-v2:1"
+	ClaudeInstant             = "anthropic.claude-instant-v1"
+	Claude3Sonnet             = "anthropic.claude-3-sonnet-20240229-v1:0"
+	Claude3Haiku              = "anthropic.claude-3-haiku-20240307-v1:0"
+	Llama2Chat13B             = "meta.llama2-13b-chat-v1"
+	Llama2Chat70B             = "meta.llama2-70b-chat-v1"
+	Llama3Instruct8B          = "meta.llama3-8b-instruct-v1:0"
+	Llama3Instruct70B         = "meta.llama3-70b-instruct-v1:0"
+	Mistral7BInstruct         = "mistral.mistral-7b-instruct-v0:2"
+	Mixtral8x7BInstruct       = "mistral.mixtral-8x7b-instruct-v0:1"
+	MistralLarge              = "mistral.mistral-large-2402-v1:0"
+	TitanTextLite             = "amazon.titan-text-lite-v1"
+	TitanTextExpress          = "amazon.titan-text-express-v1"
+	TitanTextPremier          = "amazon.titan-text-premier-v1:0"
+	CohereCommand             = "cohere.command-text-v14"
+	CohereCommandLight        = "cohere.command-light-text-v14"
+	CohereCommandR            = "cohere.command-r-v1:0"
+	CohereCommandRPlus        = "cohere.command-r-plus-v1:0"
+	AI21J2Mid                 = "ai21.j2-mid-v1"
+	AI21J2Ultra               = "ai21.j2-ultra-v1"
+	AI21JambaInstruct         = "ai21.jamba-instruct-v1:0"
+	StableDiffusionXL         = "stability.stable-diffusion-xl-v1"
+	TitanImageGeneratorV1     = "amazon.titan-image-generator-v1"
+	TitanImageGeneratorV2     = "amazon.titan-image-generator-v2:0"
+)
+
+// Cross-region inference profile IDs.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/cross-region-inference.html
+const (
+	USClaude3Haiku    = "us." + Claude3Haiku
+	USClaude3Sonnet   = "us." + Claude3Sonnet
+	EUClaude3Haiku    = "eu." + Claude3Haiku
+	EUClaude3Sonnet   = "eu." + Claude3Sonnet
+	APACClaude3Haiku  = "apac." + Claude3Haiku
+	APACClaude3Sonnet = "apac." + Claude3Sonnet
 )
@@ -0,0 +1,243 @@
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ModelFamily identifies the request codec used for a model.
+type ModelFamily string
+
+const (
+	ModelFamilyClaude2                  ModelFamily = "claude2"
+	ModelFamilyClaude3                  ModelFamily = "claude3"
+	ModelFamilyLlama2                   ModelFamily = "llama2"
+	ModelFamilyLlama3                   ModelFamily = "llama3"
+	ModelFamilyMistral                  ModelFamily = "mistral"
+	ModelFamilyTitanText                ModelFamily = "titan-text"
+	ModelFamilyCohereCommand            ModelFamily = "cohere-command"
+	ModelFamilyCohereCommandR           ModelFamily = "cohere-command-r"
+	ModelFamilyAI21Jurassic2            ModelFamily = "ai21-jurassic2"
+	ModelFamilyAI21Jamba                ModelFamily = "ai21-jamba"
+	ModelFamilyTitanEmbedding           ModelFamily = "titan-embedding"
+	ModelFamilyTitanEmbeddingV2         ModelFamily = "titan-embedding-v2"
+	ModelFamilyTitanMultimodalEmbedding ModelFamily = "titan-multimodal-embedding"
+	ModelFamilyCohereEmbed              ModelFamily = "cohere-embed"
+	ModelFamilyStableDiffusionXL        ModelFamily = "stable-diffusion-xl"
+	ModelFamilyTitanImageGenerator      ModelFamily = "titan-image-generator"
+)
+
+// IsTextGeneration reports whether the family is used by GenerateContent.
+func (f ModelFamily) IsTextGeneration() bool {
+	switch f {
+	case ModelFamilyClaude2, ModelFamilyClaude3, ModelFamilyLlama2, ModelFamilyLlama3, ModelFamilyMistral,
+		ModelFamilyTitanText, ModelFamilyCohereCommand, ModelFamilyCohereCommandR, ModelFamilyAI21Jurassic2, ModelFamilyAI21Jamba:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsEmbedding reports whether the family is used by CreateEmbedding.
+func (f ModelFamily) IsEmbedding() bool {
+	switch f {
+	case ModelFamilyTitanEmbedding, ModelFamilyTitanEmbeddingV2, ModelFamilyTitanMultimodalEmbedding, ModelFamilyCohereEmbed:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsImageGeneration reports whether the family is used by GenerateImage.
+func (f ModelFamily) IsImageGeneration() bool {
+	switch f {
+	case ModelFamilyStableDiffusionXL, ModelFamilyTitanImageGenerator:
+		return true
+	default:
+		return false
+	}
+}
+
+// ModelInfo describes a model known to this package.
+type ModelInfo struct {
+	ID     string
+	Family ModelFamily
+	// ContextWindow is the maximum number of input tokens. 0 means unknown.
+	ContextWindow int
+	// MaxOutputTokens is the maximum number of output tokens. 0 means unknown.
+	MaxOutputTokens   int
+	SupportsImages    bool
+	SupportsTools     bool
+	SupportsSystem    bool
+	SupportsStreaming bool
+	// EmbeddingDimensions is the default dimensions of embeddings for embedding models.
+	EmbeddingDimensions int
+}
+
+var modelRegistry = struct {
+	mu     sync.RWMutex
+	models map[string]ModelInfo
+}{
+	models: map[string]ModelInfo{},
+}
+
+func init() {
+	for _, info := range builtinModels {
+		if err := RegisterModel(info); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterModel registers a model, or replaces an already registered one with the same ID.
+// It can be used to add new model versions or custom models that share the codec of an existing family.
+// Capabilities that are not set are not supported, so use RegisterModelLike to inherit them from an existing model.
+func RegisterModel(info ModelInfo) error {
+	if info.ID == "" {
+		return errors.New("model id is empty")
+	}
+	if !info.Family.IsTextGeneration() && !info.Family.IsEmbedding() && !info.Family.IsImageGeneration() {
+		return fmt.Errorf("model family `%s` not supported", info.Family)
+	}
+	modelRegistry.mu.Lock()
+	defer modelRegistry.mu.Unlock()
+	modelRegistry.models[info.ID] = info
+	return nil
+}
+
+// RegisterModelLike registers a model with the family and capabilities of the registered base model,
+// e.g. a new version of a Claude 3 model.
+func RegisterModelLike(id string, baseModelID string) error {
+	info, ok := LookupModel(baseModelID)
+	if !ok {
+		return fmt.Errorf("model `%s` not registered", baseModelID)
+	}
+	info.ID = id
+	return RegisterModel(info)
+}
+
+// LookupModel returns the registered model info of the model ID.
+func LookupModel(id string) (ModelInfo, bool) {
+	modelRegistry.mu.RLock()
+	defer modelRegistry.mu.RUnlock()
+	info, ok := modelRegistry.models[id]
+	return info, ok
+}
+
+// foundationModelARNPrefix is the resource prefix of foundation model ARNs, e.g.
+// arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-v2
+const foundationModelARNPrefix = ":foundation-model/"
+
+// inferenceProfileARNPrefix is the resource prefix of inference profile ARNs, e.g.
+// arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-haiku-20240307-v1:0
+const inferenceProfileARNPrefix = ":inference-profile/"
+
+// inferenceProfilePrefixes are the region prefixes of cross-region inference profile IDs.
+var inferenceProfilePrefixes = []string{"us.", "eu.", "apac."}
+
+// resolveModelID resolves a model ARN or inference profile ID to the foundation model ID.
+func resolveModelID(id string) string {
+	if strings.HasPrefix(id, "arn:") {
+		if _, after, ok := strings.Cut(id, foundationModelARNPrefix); ok {
+			return after
+		}
+		if _, after, ok := strings.Cut(id, inferenceProfileARNPrefix); ok {
+			id = after
+		}
+	}
+	for _, prefix := range inferenceProfilePrefixes {
+		if after, ok := strings.CutPrefix(id, prefix); ok {
+			return after
+		}
+	}
+	return id
+}
+
+// lookupModel returns the model info of the model ID, model ARN or alias.
+// Aliases configured by WithModelAlias and WithModelARN are resolved to the base model, and foundation model ARNs,
+// inference profile IDs and ARNs are resolved to the model ID. The returned info has the given id as ID.
+func (l *LLM) lookupModel(id string) (ModelInfo, bool) {
+	resolved := resolveModelID(id)
+	if base, ok := l.modelAliases[id]; ok {
+		resolved = resolveModelID(base)
+	}
+	info, ok := LookupModel(id)
+	if !ok {
+		info, ok = LookupModel(resolved)
+	}
+	if !ok {
+		return ModelInfo{}, false
+	}
+	info.ID = id
+	return info, true
+}
+
+// lookupModelFamily returns the family of the model, or empty if the model is not known.
+func (l *LLM) lookupModelFamily(id string) ModelFamily {
+	info, _ := l.lookupModel(id)
+	return info.Family
+}
+
+// validateModelCapabilities checks that the messages and call options are supported by the model.
+func validateModelCapabilities(info ModelInfo, messages []llms.MessageContent, opts *llms.CallOptions) error {
+	if info.MaxOutputTokens > 0 && opts.MaxTokens > info.MaxOutputTokens {
+		return newError(ErrValidation, "max tokens %d exceeds the limit %d of model `%s`", opts.MaxTokens, info.MaxOutputTokens, info.ID)
+	}
+	if !info.SupportsTools && (len(opts.Tools) > 0 || len(opts.Functions) > 0) {
+		return newError(ErrValidation, "model `%s` does not support tools", info.ID)
+	}
+	if !info.SupportsStreaming && opts.StreamingFunc != nil {
+		return newError(ErrValidation, "model `%s` does not support streaming", info.ID)
+	}
+	for _, message := range messages {
+		if !info.SupportsSystem && message.Role == llms.ChatMessageTypeSystem {
+			return newError(ErrUnsupportedContent, "model `%s` does not support system messages", info.ID)
+		}
+		if info.SupportsImages {
+			continue
+		}
+		for _, part := range message.Parts {
+			switch part.(type) {
+			case llms.BinaryContent, llms.ImageURLContent:
+				return newError(ErrUnsupportedContent, "model `%s` does not support images", info.ID)
+			}
+		}
+	}
+	return nil
+}
+
+var builtinModels = []ModelInfo{
+	{ID: Claude2, Family: ModelFamilyClaude2, ContextWindow: 200000, MaxOutputTokens: 4096, SupportsSystem: true, SupportsStreaming: true},
+	{ID: ClaudeInstant, Family: ModelFamilyClaude2, ContextWindow: 100000, MaxOutputTokens: 4096, SupportsSystem: true, SupportsStreaming: true},
+	{ID: Claude3Sonnet, Family: ModelFamilyClaude3, ContextWindow: 200000, MaxOutputTokens: 4096, SupportsImages: true, SupportsTools: true, SupportsSystem: true, SupportsStreaming: true},
+	{ID: Claude3Haiku, Family: ModelFamilyClaude3, ContextWindow: 200000, MaxOutputTokens: 4096, SupportsImages: true, SupportsTools: true, SupportsSystem: true, SupportsStreaming: true},
+	{ID: Llama2Chat13B, Family: ModelFamilyLlama2, ContextWindow: 4096, MaxOutputTokens: 2048, SupportsSystem: true},
+	{ID: Llama2Chat70B, Family: ModelFamilyLlama2, ContextWindow: 4096, MaxOutputTokens: 2048, SupportsSystem: true},
+	{ID: Llama3Instruct8B, Family: ModelFamilyLlama3, ContextWindow: 8192, MaxOutputTokens: 2048, SupportsSystem: true},
+	{ID: Llama3Instruct70B, Family: ModelFamilyLlama3, ContextWindow: 8192, MaxOutputTokens: 2048, SupportsSystem: true},
+	{ID: Mistral7BInstruct, Family: ModelFamilyMistral, ContextWindow: 32000, MaxOutputTokens: 8192, SupportsSystem: true},
+	{ID: Mixtral8x7BInstruct, Family: ModelFamilyMistral, ContextWindow: 32000, MaxOutputTokens: 4096, SupportsSystem: true},
+	{ID: MistralLarge, Family: ModelFamilyMistral, ContextWindow: 32000, MaxOutputTokens: 8192, SupportsSystem: true},
+	{ID: TitanTextLite, Family: ModelFamilyTitanText, ContextWindow: 4096, MaxOutputTokens: 4096, SupportsSystem: true},
+	{ID: TitanTextExpress, Family: ModelFamilyTitanText, ContextWindow: 8192, MaxOutputTokens: 8192, SupportsSystem: true},
+	{ID: TitanTextPremier, Family: ModelFamilyTitanText, ContextWindow: 32000, MaxOutputTokens: 3072, SupportsSystem: true},
+	{ID: CohereCommand, Family: ModelFamilyCohereCommand, ContextWindow: 4096, MaxOutputTokens: 4000, SupportsSystem: true},
+	{ID: CohereCommandLight, Family: ModelFamilyCohereCommand, ContextWindow: 4096, MaxOutputTokens: 4000, SupportsSystem: true},
+	{ID: CohereCommandR, Family: ModelFamilyCohereCommandR, ContextWindow: 128000, MaxOutputTokens: 4000, SupportsSystem: true},
+	{ID: CohereCommandRPlus, Family: ModelFamilyCohereCommandR, ContextWindow: 128000, MaxOutputTokens: 4000, SupportsSystem: true},
+	{ID: AI21J2Mid, Family: ModelFamilyAI21Jurassic2, ContextWindow: 8191, MaxOutputTokens: 8191, SupportsSystem: true},
+	{ID: AI21J2Ultra, Family: ModelFamilyAI21Jurassic2, ContextWindow: 8191, MaxOutputTokens: 8191, SupportsSystem: true},
+	{ID: AI21JambaInstruct, Family: ModelFamilyAI21Jamba, ContextWindow: 256000, MaxOutputTokens: 4096, SupportsSystem: true},
+	{ID: TitanEmbeddingG1Text, Family: ModelFamilyTitanEmbedding, ContextWindow: 8192, EmbeddingDimensions: 1536},
+	{ID: TitanEmbedTextV2, Family: ModelFamilyTitanEmbeddingV2, ContextWindow: 8192, EmbeddingDimensions: 1024},
+	{ID: TitanEmbedImageV1, Family: ModelFamilyTitanMultimodalEmbedding, ContextWindow: 128, SupportsImages: true, EmbeddingDimensions: 1024},
+	{ID: CohereEmbedEnglishV3, Family: ModelFamilyCohereEmbed, ContextWindow: 512, EmbeddingDimensions: 1024},
+	{ID: CohereEmbedMultilingualV3, Family: ModelFamilyCohereEmbed, ContextWindow: 512, EmbeddingDimensions: 1024},
+	{ID: StableDiffusionXL, Family: ModelFamilyStableDiffusionXL, ContextWindow: 77},
+	{ID: TitanImageGeneratorV1, Family: ModelFamilyTitanImageGenerator, ContextWindow: 512, SupportsImages: true},
+	{ID: TitanImageGeneratorV2, Family: ModelFamilyTitanImageGenerator, ContextWindow: 512, SupportsImages: true},
+}
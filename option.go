@@ -15,33 +15,110 @@ type BedrockClient interface {
 	InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
 }
 
+// BedrockStreamClient is an optional interface of BedrockClient, required for streaming with llms.WithStreamingFunc.
+type BedrockStreamClient interface {
+	InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error)
+}
+
+// BedrockConverseClient is an optional interface of BedrockClient, required by WithConverseAPI.
+type BedrockConverseClient interface {
+	Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error)
+}
+
+// BedrockConverseStreamClient is an optional interface of BedrockClient, required for streaming with WithConverseAPI.
+type BedrockConverseStreamClient interface {
+	ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error)
+}
+
+// asOptionalClient returns the client as the optional interface T, or an ErrValidation error if it is not implemented.
+func asOptionalClient[T any](client BedrockClient, method string) (T, error) {
+	c, ok := client.(T)
+	if !ok {
+		return c, newError(ErrValidation, "client %T does not implement %s", client, method)
+	}
+	return c, nil
+}
+
+// operation is a Bedrock Runtime API operation of a client. err is set if the client does not implement the operation.
+type operation[In, Out any] struct {
+	name string
+	call func(ctx context.Context, params In, optFns ...func(*bedrockruntime.Options)) (Out, error)
+	err  error
+}
+
+// optionalOperation returns the operation of the optional interface T of client.
+func optionalOperation[T, In, Out any](client BedrockClient, name string, method func(T, context.Context, In, ...func(*bedrockruntime.Options)) (Out, error)) operation[In, Out] {
+	c, err := asOptionalClient[T](client, name)
+	if err != nil {
+		return operation[In, Out]{name: name, err: err}
+	}
+	return operation[In, Out]{
+		name: name,
+		call: func(ctx context.Context, params In, optFns ...func(*bedrockruntime.Options)) (Out, error) {
+			return method(c, ctx, params, optFns...)
+		},
+	}
+}
+
+func invokeModelOperation(client BedrockClient) operation[*bedrockruntime.InvokeModelInput, *bedrockruntime.InvokeModelOutput] {
+	return operation[*bedrockruntime.InvokeModelInput, *bedrockruntime.InvokeModelOutput]{name: "InvokeModel", call: client.InvokeModel}
+}
+
+func invokeModelWithResponseStreamOperation(client BedrockClient) operation[*bedrockruntime.InvokeModelWithResponseStreamInput, *bedrockruntime.InvokeModelWithResponseStreamOutput] {
+	return optionalOperation(client, "InvokeModelWithResponseStream", BedrockStreamClient.InvokeModelWithResponseStream)
+}
+
+func converseOperation(client BedrockClient) operation[*bedrockruntime.ConverseInput, *bedrockruntime.ConverseOutput] {
+	return optionalOperation(client, "Converse", BedrockConverseClient.Converse)
+}
+
+func converseStreamOperation(client BedrockClient) operation[*bedrockruntime.ConverseStreamInput, *bedrockruntime.ConverseStreamOutput] {
+	return optionalOperation(client, "ConverseStream", BedrockConverseStreamClient.ConverseStream)
+}
+
 type options struct {
-	region         string
-	embeddingModel string
-	model          string
-	awsCfg         *aws.Config
-	client         BedrockClient
-	numWorkers     int
-	logger         *slog.Logger
-	maxTokens      int
-	temperature    float64
-	topP           float64
-	topK           int
-	stopWords      []string
+	region              string
+	regions             []string
+	regionClients       []regionClient
+	embeddingModel      string
+	embeddingDimensions int
+	embeddingNormalize  *bool
+	embeddingInputType  string
+	model               string
+	modelAliases        map[string]string
+	fallbackModels      []string
+	imageModel          string
+	awsCfg              *aws.Config
+	client              BedrockClient
+	numWorkers          int
+	logger              *slog.Logger
+	maxTokens           int
+	temperature         float64
+	topP                float64
+	topK                int
+	stopWords           []string
+	frequencyPenalty    float64
+	presencePenalty     float64
+	useConverseAPI      bool
+	retryPolicy         *RetryPolicy
+	rateLimits          map[string]RateLimit
+	circuitBreaker      *CircuitBreakerPolicy
+	usageTracker        *UsageTracker
 }
 
 func newOptions() *options {
 	return &options{
-		region:         os.Getenv("AWS_REGION"),
-		embeddingModel: TitanEmbeddingG1Text,
-		model:          ClaudeInstant,
-		numWorkers:     10,
-		logger:         slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
-		maxTokens:      1000,
-		temperature:    0.7,
-		topK:           50,
-		topP:           0.9,
-		stopWords:      []string{"Human:"},
+		region:             os.Getenv("AWS_REGION"),
+		embeddingModel:     TitanEmbeddingG1Text,
+		embeddingInputType: CohereEmbedInputTypeSearchDocument,
+		model:              ClaudeInstant,
+		imageModel:         TitanImageGeneratorV1,
+		numWorkers:         10,
+		logger:             slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		maxTokens:          1000,
+		temperature:        0.7,
+		topK:               50,
+		topP:               0.9,
 	}
 }
 
@@ -55,7 +132,15 @@ func (o *options) newBedrockClient(ctx context.Context) (BedrockClient, error) {
 	if o.client != nil {
 		return o.client, nil
 	}
+	client, err := o.newBedrockClientForRegion(ctx, o.region)
+	if err != nil {
+		return nil, err
+	}
+	o.client = client
+	return o.client, nil
+}
 
+func (o *options) newBedrockClientForRegion(ctx context.Context, region string) (BedrockClient, error) {
 	if o.awsCfg == nil {
 		awsCfg, err := config.LoadDefaultConfig(ctx)
 		if err != nil {
@@ -65,13 +150,35 @@ func (o *options) newBedrockClient(ctx context.Context) (BedrockClient, error) {
 	}
 
 	bedrockOpts := []func(*bedrockruntime.Options){}
-	if o.region != "" {
+	if region != "" {
 		bedrockOpts = append(bedrockOpts, func(bo *bedrockruntime.Options) {
-			bo.Region = o.region
+			bo.Region = region
 		})
 	}
-	o.client = bedrockruntime.NewFromConfig(*o.awsCfg, bedrockOpts...)
-	return o.client, nil
+	if o.retryPolicy != nil {
+		// disable the SDK retries, so that every attempt goes through the rate limiter and the circuit breaker.
+		bedrockOpts = append(bedrockOpts, func(bo *bedrockruntime.Options) {
+			bo.RetryMaxAttempts = 1
+		})
+	}
+	return bedrockruntime.NewFromConfig(*o.awsCfg, bedrockOpts...), nil
+}
+
+// newRegionClients returns the clients of the regions configured by WithRegions and WithRegionClient in order.
+// It returns nil if multi-region is not configured.
+func (o *options) newRegionClients(ctx context.Context) ([]regionClient, error) {
+	if len(o.regionClients) > 0 {
+		return o.regionClients, nil
+	}
+	clients := make([]regionClient, 0, len(o.regions))
+	for _, region := range o.regions {
+		client, err := o.newBedrockClientForRegion(ctx, region)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, regionClient{region: region, client: client})
+	}
+	return clients, nil
 }
 
 type Option func(*options)
@@ -82,18 +189,95 @@ func WithRegion(region string) Option {
 	}
 }
 
+// WithRegions sets the ordered list of regions to fail over on throttling, server errors,
+// or models that are not available in the region. The region that served the request is recorded
+// in GenerationInfo["region"] of GenerateContent.
+func WithRegions(regions ...string) Option {
+	return func(o *options) {
+		o.regions = regions
+	}
+}
+
+// WithRegionClient appends a client of the region to fail over in order, instead of WithRegions.
+func WithRegionClient(region string, client BedrockClient) Option {
+	return func(o *options) {
+		o.regionClients = append(o.regionClients, regionClient{region: region, client: client})
+	}
+}
+
 func WithModel(model string) Option {
 	return func(o *options) {
 		o.model = model
 	}
 }
 
+// WithModelARN sets the model to a Provisioned Throughput or custom model ARN, registered by WithModelAlias.
+// It is the same as WithModelAlias followed by WithModel(arn), so a later WithModel or WithModelARN sets another model.
+func WithModelARN(arn string, baseModel string) Option {
+	return func(o *options) {
+		WithModelAlias(arn, baseModel)(o)
+		o.model = arn
+	}
+}
+
+// WithModelAlias registers a Provisioned Throughput or custom model ARN without changing the model.
+// baseModel is the model ID that the ARN is based on (e.g. ClaudeInstant), and its request codec is used,
+// while the ARN is sent as the model ID. It can be specified multiple times for per-call model override with llms.WithModel.
+func WithModelAlias(arn string, baseModel string) Option {
+	return func(o *options) {
+		if o.modelAliases == nil {
+			o.modelAliases = make(map[string]string)
+		}
+		o.modelAliases[arn] = baseModel
+	}
+}
+
+// WithFallbackModels sets the ordered list of models that GenerateContent tries when the model fails
+// with throttling, context window exceeded or availability errors.
+// The model that answered is recorded in GenerationInfo["model"].
+func WithFallbackModels(models ...string) Option {
+	return func(o *options) {
+		o.fallbackModels = models
+	}
+}
+
+// WithImageModel sets the model used by GenerateImage.
+func WithImageModel(imageModel string) Option {
+	return func(o *options) {
+		o.imageModel = imageModel
+	}
+}
+
 func WithEmbeddingModel(embeddingModel string) Option {
 	return func(o *options) {
 		o.embeddingModel = embeddingModel
 	}
 }
 
+// WithEmbeddingDimensions sets the number of dimensions of the output embedding.
+// Titan Embed Text v2 supports 256, 512 and 1024 (default).
+func WithEmbeddingDimensions(dimensions int) Option {
+	return func(o *options) {
+		o.embeddingDimensions = dimensions
+	}
+}
+
+// WithEmbeddingNormalize sets whether the output embedding is normalized.
+// Titan Embed Text v2 normalizes embeddings by default.
+func WithEmbeddingNormalize(normalize bool) Option {
+	return func(o *options) {
+		o.embeddingNormalize = &normalize
+	}
+}
+
+// WithEmbeddingInputType sets the default input type for Cohere Embed models.
+// It can be overridden per call with ContextWithEmbeddingInputType.
+func WithEmbeddingInputType(inputType string) Option {
+	return func(o *options) {
+		o.embeddingInputType = inputType
+	}
+}
+
 func WithAWSConfig(cfg aws.Config) Option {
 	return func(o *options) {
 		o.awsCfg = &cfg
@@ -147,3 +331,59 @@ func WithStopWords(stopWords []string) Option {
 		o.stopWords = stopWords
 	}
 }
+
+func WithFrequencyPenalty(frequencyPenalty float64) Option {
+	return func(o *options) {
+		o.frequencyPenalty = frequencyPenalty
+	}
+}
+
+func WithPresencePenalty(presencePenalty float64) Option {
+	return func(o *options) {
+		o.presencePenalty = presencePenalty
+	}
+}
+
+// WithConverseAPI enables the Converse (and ConverseStream) API for GenerateContent.
+// When enabled, any chat model supported by the Converse API can be used without model specific request codec.
+func WithConverseAPI(useConverseAPI bool) Option {
+	return func(o *options) {
+		o.useConverseAPI = useConverseAPI
+	}
+}
+
+// WithRetryPolicy enables retries of Bedrock Runtime API calls for generation and embedding.
+// Use DefaultRetryPolicy for retries of throttling and transient errors.
+// It replaces the retries of the AWS SDK in clients created by the LLM. A client passed by WithClient or WithRegionClient
+// keeps its own retryer, so configure it with RetryMaxAttempts = 1 to avoid multiplying the attempts.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = &policy
+	}
+}
+
+// WithRateLimit sets the client side rate limit of the model ID.
+// The limit is shared by GenerateContent, CreateEmbedding and the other calls of the LLM, including retries.
+func WithRateLimit(modelID string, limit RateLimit) Option {
+	return func(o *options) {
+		if o.rateLimits == nil {
+			o.rateLimits = make(map[string]RateLimit)
+		}
+		o.rateLimits[modelID] = limit
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker per model ID, that fails fast with ErrCircuitOpen while the model is degraded.
+// State changes are logged, and reported to LLM.CallbacksHandler if it implements CircuitBreakerHandler.
+func WithCircuitBreaker(policy CircuitBreakerPolicy) Option {
+	return func(o *options) {
+		o.circuitBreaker = &policy
+	}
+}
+
+// WithUsageTracker records the token usage and estimated cost of GenerateContent and CreateEmbedding to the tracker.
+func WithUsageTracker(tracker *UsageTracker) Option {
+	return func(o *options) {
+		o.usageTracker = tracker
+	}
+}
@@ -0,0 +1,218 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// RateLimit is the client side rate limit of a model.
+// Zero values mean unlimited.
+type RateLimit struct {
+	RequestsPerSecond float64
+	// TokensPerMinute limits the input tokens per minute.
+	// Input tokens are estimated before the call from the length of the text in the request, about 4 characters per token.
+	// Images are not counted.
+	TokensPerMinute int
+}
+
+// tokenBucket is a token bucket that refills at rate per second up to capacity.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, capacity float64) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// reserve takes n tokens from the bucket and returns how long the caller must wait before using them.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	n = min(n, b.capacity)
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type modelRateLimiter struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+func newModelRateLimiter(limit RateLimit) *modelRateLimiter {
+	l := &modelRateLimiter{}
+	if limit.RequestsPerSecond > 0 {
+		l.requests = newTokenBucket(limit.RequestsPerSecond, 1)
+	}
+	if limit.TokensPerMinute > 0 {
+		l.tokens = newTokenBucket(float64(limit.TokensPerMinute)/60, float64(limit.TokensPerMinute))
+	}
+	return l
+}
+
+func (l *modelRateLimiter) wait(ctx context.Context, estimatedTokens int) (time.Duration, error) {
+	var delay time.Duration
+	if l.requests != nil {
+		delay = max(delay, l.requests.reserve(1))
+	}
+	if l.tokens != nil {
+		delay = max(delay, l.tokens.reserve(float64(estimatedTokens)))
+	}
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return delay, context.Cause(ctx)
+	case <-timer.C:
+		return delay, nil
+	}
+}
+
+// rateLimitClient is a BedrockClient that waits for the rate limit of the model before calling the underlying client.
+type rateLimitClient struct {
+	client   BedrockClient
+	limiters map[string]*modelRateLimiter
+	logger   *slog.Logger
+}
+
+var (
+	_ BedrockClient               = (*rateLimitClient)(nil)
+	_ BedrockStreamClient         = (*rateLimitClient)(nil)
+	_ BedrockConverseClient       = (*rateLimitClient)(nil)
+	_ BedrockConverseStreamClient = (*rateLimitClient)(nil)
+)
+
+func newRateLimitClient(client BedrockClient, limits map[string]RateLimit, logger *slog.Logger) *rateLimitClient {
+	limiters := make(map[string]*modelRateLimiter, len(limits))
+	for modelID, limit := range limits {
+		limiters[modelID] = newModelRateLimiter(limit)
+	}
+	return &rateLimitClient{
+		client:   client,
+		limiters: limiters,
+		logger:   logger,
+	}
+}
+
+func (c *rateLimitClient) wait(ctx context.Context, modelID *string, estimatedTokens int) error {
+	limiter, ok := c.limiters[aws.ToString(modelID)]
+	if !ok {
+		return nil
+	}
+	delay, err := limiter.wait(ctx, estimatedTokens)
+	if delay > 0 {
+		c.logger.Debug("rate limited", "model", aws.ToString(modelID), "delay", delay, "estimated_tokens", estimatedTokens)
+	}
+	return err
+}
+
+// imageDataKeys are the JSON keys of InvokeModel request bodies that hold base64 encoded images.
+var imageDataKeys = map[string]bool{
+	"data":       true,
+	"image":      true,
+	"images":     true,
+	"inputImage": true,
+	"maskImage":  true,
+}
+
+// estimateTokens estimates the number of input tokens of an InvokeModel request body
+// by the string values of the JSON, except for the image data.
+func estimateTokens(body []byte) int {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return estimateTokensFromLength(len(body))
+	}
+	return estimateTokensFromLength(jsonTextLength(v))
+}
+
+func jsonTextLength(v any) int {
+	var n int
+	switch v := v.(type) {
+	case string:
+		n = len(v)
+	case []any:
+		for _, e := range v {
+			n += jsonTextLength(e)
+		}
+	case map[string]any:
+		for k, e := range v {
+			if !imageDataKeys[k] {
+				n += jsonTextLength(e)
+			}
+		}
+	}
+	return n
+}
+
+func estimateTokensFromLength(n int) int {
+	return n/4 + 1
+}
+
+// estimateConverseTokens estimates the number of input tokens of Converse messages by their text, except for images.
+func estimateConverseTokens(system []types.SystemContentBlock, messages []types.Message) int {
+	var n int
+	for _, block := range system {
+		if text, ok := block.(*types.SystemContentBlockMemberText); ok {
+			n += len(text.Value)
+		}
+	}
+	for _, message := range messages {
+		for _, block := range message.Content {
+			if text, ok := block.(*types.ContentBlockMemberText); ok {
+				n += len(text.Value)
+			}
+		}
+	}
+	return estimateTokensFromLength(n)
+}
+
+func withRateLimit[In, Out any](ctx context.Context, c *rateLimitClient, op operation[In, Out], modelID *string, estimatedTokens int, params In, optFns []func(*bedrockruntime.Options)) (Out, error) {
+	var zero Out
+	if op.err != nil {
+		return zero, op.err
+	}
+	if err := c.wait(ctx, modelID, estimatedTokens); err != nil {
+		return zero, err
+	}
+	return op.call(ctx, params, optFns...)
+}
+
+func (c *rateLimitClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	return withRateLimit(ctx, c, invokeModelOperation(c.client), params.ModelId, estimateTokens(params.Body), params, optFns)
+}
+
+func (c *rateLimitClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return withRateLimit(ctx, c, invokeModelWithResponseStreamOperation(c.client), params.ModelId, estimateTokens(params.Body), params, optFns)
+}
+
+func (c *rateLimitClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	return withRateLimit(ctx, c, converseOperation(c.client), params.ModelId, estimateConverseTokens(params.System, params.Messages), params, optFns)
+}
+
+func (c *rateLimitClient) ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error) {
+	return withRateLimit(ctx, c, converseStreamOperation(c.client), params.ModelId, estimateConverseTokens(params.System, params.Messages), params, optFns)
+}
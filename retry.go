@@ -0,0 +1,153 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go"
+)
+
+// RetryPolicy configures retries of Bedrock Runtime API calls.
+// Streaming calls are retried only when opening the stream fails.
+// Zero fields other than Jitter are filled with the values of DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first call. 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each subsequent retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0.0-1.0) of each delay that is randomized.
+	Jitter float64
+	// RetryableErrorCodes are the API error codes to retry, e.g. ThrottlingException.
+	RetryableErrorCodes []string
+}
+
+// DefaultRetryPolicy returns a RetryPolicy that retries throttling and transient errors up to 5 attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    20 * time.Second,
+		Jitter:      0.5,
+		RetryableErrorCodes: []string{
+			"ThrottlingException",
+			"ModelNotReadyException",
+			"ServiceUnavailableException",
+			"InternalServerException",
+			"ModelTimeoutException",
+		},
+	}
+}
+
+// withDefaults returns the policy with zero fields filled with the values of DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if len(p.RetryableErrorCodes) == 0 {
+		p.RetryableErrorCodes = d.RetryableErrorCodes
+	}
+	return p
+}
+
+// IsRetryable reports whether err is an API error with one of the RetryableErrorCodes.
+func (p RetryPolicy) IsRetryable(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.Contains(p.RetryableErrorCodes, apiErr.ErrorCode())
+}
+
+// delay returns the delay before the retry following the attempt (1-origin).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		jitter := time.Duration(float64(d) * min(p.Jitter, 1.0) * rand.Float64())
+		d -= jitter
+	}
+	return d
+}
+
+// retryClient is a BedrockClient that retries the calls of the underlying client according to the policy.
+type retryClient struct {
+	client BedrockClient
+	policy RetryPolicy
+	logger *slog.Logger
+}
+
+var (
+	_ BedrockClient               = (*retryClient)(nil)
+	_ BedrockStreamClient         = (*retryClient)(nil)
+	_ BedrockConverseClient       = (*retryClient)(nil)
+	_ BedrockConverseStreamClient = (*retryClient)(nil)
+)
+
+func newRetryClient(client BedrockClient, policy RetryPolicy, logger *slog.Logger) *retryClient {
+	return &retryClient{
+		client: client,
+		policy: policy.withDefaults(),
+		logger: logger,
+	}
+}
+
+func withRetry[In, Out any](ctx context.Context, c *retryClient, op operation[In, Out], modelID *string, params In, optFns []func(*bedrockruntime.Options)) (Out, error) {
+	if op.err != nil {
+		var zero Out
+		return zero, op.err
+	}
+	for attempt := 1; ; attempt++ {
+		output, err := op.call(ctx, params, optFns...)
+		if err == nil || attempt >= c.policy.MaxAttempts || !c.policy.IsRetryable(err) {
+			return output, err
+		}
+		delay := c.policy.delay(attempt)
+		c.logger.Warn("retrying bedrock runtime call",
+			"operation", op.name,
+			"model", aws.ToString(modelID),
+			"attempt", attempt,
+			"delay", delay,
+			"err", err,
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return output, errors.Join(err, context.Cause(ctx))
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *retryClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	return withRetry(ctx, c, invokeModelOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *retryClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return withRetry(ctx, c, invokeModelWithResponseStreamOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *retryClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	return withRetry(ctx, c, converseOperation(c.client), params.ModelId, params, optFns)
+}
+
+func (c *retryClient) ConverseStream(ctx context.Context, params *bedrockruntime.ConverseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseStreamOutput, error) {
+	return withRetry(ctx, c, converseStreamOperation(c.client), params.ModelId, params, optFns)
+}
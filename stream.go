@@ -0,0 +1,44 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// invokeModelWithResponseStream invokes the model with the response stream API and calls fn for each received chunk.
+// If fn returns an error, the stream is closed and the error is returned.
+func (l *LLM) invokeModelWithResponseStream(ctx context.Context, modelID string, body []byte, fn func(chunk []byte) error) error {
+	client, err := asOptionalClient[BedrockStreamClient](l.client, "InvokeModelWithResponseStream")
+	if err != nil {
+		return err
+	}
+	output, err := client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return wrapAPIError("failed to invoke model with response stream", err)
+	}
+	stream := output.GetStream()
+	defer stream.Close()
+	for event := range stream.Events() {
+		switch e := event.(type) {
+		case *types.ResponseStreamMemberChunk:
+			addInvocationUsageFromChunk(ctx, e.Value.Bytes)
+			if err := fn(e.Value.Bytes); err != nil {
+				return err
+			}
+		default:
+			l.logger.Debug("unknown response stream event", "type", fmt.Sprintf("%T", e))
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return wrapAPIError("failed to read response stream", err)
+	}
+	return nil
+}
@@ -2,16 +2,20 @@ package bedrock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 )
 
 type titanEmbeddingRequest struct {
-	InputText string `json:"inputText"`
+	InputText       string                       `json:"inputText,omitempty"`
+	InputImage      string                       `json:"inputImage,omitempty"`
+	Dimensions      int                          `json:"dimensions,omitempty"`
+	Normalize       *bool                        `json:"normalize,omitempty"`
+	EmbeddingConfig *titanEmbeddingRequestConfig `json:"embeddingConfig,omitempty"`
+}
+
+type titanEmbeddingRequestConfig struct {
+	OutputEmbeddingLength int `json:"outputEmbeddingLength"`
 }
 
 type titanEmbeddingResponse struct {
@@ -20,12 +24,69 @@ type titanEmbeddingResponse struct {
 }
 
 type titanEmbdddingJob struct {
-	index int
-	text  string
+	index   int
+	payload *titanEmbeddingRequest
+}
+
+func (l *LLM) validateTitanEmbeddingOptions() error {
+	switch l.lookupModelFamily(l.embeddingModel) {
+	case ModelFamilyTitanEmbeddingV2:
+		switch l.embeddingDimensions {
+		case 0, 256, 512, 1024:
+			return nil
+		default:
+			return newError(ErrValidation, "embedding dimensions %d not supported, must be 256, 512 or 1024", l.embeddingDimensions)
+		}
+	case ModelFamilyTitanMultimodalEmbedding:
+		if l.embeddingNormalize != nil {
+			return newError(ErrValidation, "embedding model `%s` does not support normalize", l.embeddingModel)
+		}
+		switch l.embeddingDimensions {
+		case 0, 256, 384, 1024:
+			return nil
+		default:
+			return newError(ErrValidation, "embedding dimensions %d not supported, must be 256, 384 or 1024", l.embeddingDimensions)
+		}
+	default:
+		if l.embeddingDimensions != 0 || l.embeddingNormalize != nil {
+			return newError(ErrValidation, "embedding model `%s` does not support dimensions and normalize", l.embeddingModel)
+		}
+		return nil
+	}
+}
+
+// newTitanEmbeddingRequest returns a request body with the embedding options of the LLM, but without inputs.
+func (l *LLM) newTitanEmbeddingRequest() *titanEmbeddingRequest {
+	payload := &titanEmbeddingRequest{}
+	switch l.lookupModelFamily(l.embeddingModel) {
+	case ModelFamilyTitanEmbeddingV2:
+		payload.Dimensions = l.embeddingDimensions
+		payload.Normalize = l.embeddingNormalize
+	case ModelFamilyTitanMultimodalEmbedding:
+		if l.embeddingDimensions != 0 {
+			payload.EmbeddingConfig = &titanEmbeddingRequestConfig{
+				OutputEmbeddingLength: l.embeddingDimensions,
+			}
+		}
+	}
+	return payload
 }
 
-func (l *LLM) createEmbeddingWithTaitan(ctx context.Context, texts []string) ([][]float32, error) {
-	embeddings := make([][]float32, len(texts))
+func (l *LLM) createEmbeddingWithTaitan(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	if err := l.validateTitanEmbeddingOptions(); err != nil {
+		return nil, err
+	}
+	payloads := make([]*titanEmbeddingRequest, len(texts))
+	for i, text := range texts {
+		payloads[i] = l.newTitanEmbeddingRequest()
+		payloads[i].InputText = text
+	}
+	return l.runTitanEmbeddingJobs(ctx, payloads)
+}
+
+func (l *LLM) runTitanEmbeddingJobs(ctx context.Context, payloads []*titanEmbeddingRequest) (*EmbeddingResponse, error) {
+	embeddings := make([][]float32, len(payloads))
+	tokenCounts := make([]int, len(payloads))
 	jobs := make(chan titanEmbdddingJob, l.numWorkers)
 	var wg sync.WaitGroup
 	cctx, cancel := context.WithCancelCause(ctx)
@@ -43,22 +104,29 @@ func (l *LLM) createEmbeddingWithTaitan(ctx context.Context, texts []string) ([]
 					return
 				default:
 				}
-				l.logger.Debug("create embedding", "id", id, "index", j.index, "text", j.text)
-				embedding, err := l.createEmbeddingWithTaitanImpl(cctx, j.text)
+				l.logger.Debug("create embedding", "id", id, "index", j.index, "text", j.payload.InputText)
+				embedding, tokenCount, err := l.createEmbeddingWithTaitanImpl(cctx, j.payload)
 				if err != nil {
 					l.logger.Debug("failed to create embedding", "id", id, "err", err)
 					cancel(fmt.Errorf("failed to create embedding for text %d: %w", j.index, err))
 					return
 				}
 				embeddings[j.index] = embedding
+				tokenCounts[j.index] = tokenCount
 			}
 			l.logger.Debug("finish embedding worker", "id", id)
 		}(w, jobs)
 	}
-	for i, text := range texts {
-		jobs <- titanEmbdddingJob{
-			index: i,
-			text:  text,
+	// stop sending jobs once a worker failed, since the workers have exited and nothing drains the channel.
+produce:
+	for i, payload := range payloads {
+		select {
+		case jobs <- titanEmbdddingJob{
+			index:   i,
+			payload: payload,
+		}:
+		case <-cctx.Done():
+			break produce
 		}
 	}
 	close(jobs)
@@ -66,34 +134,21 @@ func (l *LLM) createEmbeddingWithTaitan(ctx context.Context, texts []string) ([]
 	if err := context.Cause(cctx); err != nil {
 		return nil, err
 	}
-	return embeddings, nil
+	return &EmbeddingResponse{
+		Embeddings:       embeddings,
+		InputTokenCounts: tokenCounts,
+	}, nil
 }
 
-func (l *LLM) createEmbeddingWithTaitanImpl(ctx context.Context, text string) ([]float32, error) {
-	payload := &titanEmbeddingRequest{
-		InputText: text,
-	}
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	output, err := l.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(l.embeddingModel),
-		Body:        payloadBytes,
-		ContentType: aws.String("application/json"),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
-	}
+func (l *LLM) createEmbeddingWithTaitanImpl(ctx context.Context, payload *titanEmbeddingRequest) ([]float32, int, error) {
 	var resp titanEmbeddingResponse
-	if err := json.Unmarshal(output.Body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := l.invokeModelWithJSON(ctx, l.embeddingModel, payload, &resp); err != nil {
+		return nil, 0, err
 	}
 	embedding := make([]float32, len(resp.Embedding))
 	for i, v := range resp.Embedding {
 		embedding[i] = float32(v)
 	}
-	l.logger.Debug("embedding created", "text", text, "token_count", resp.InputTextTokenCount)
-	return embedding, nil
+	l.logger.Debug("embedding created", "text", payload.InputText, "token_count", resp.InputTextTokenCount)
+	return embedding, resp.InputTextTokenCount, nil
 }
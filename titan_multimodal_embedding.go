@@ -0,0 +1,72 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// CreateMultimodalEmbedding creates embeddings of texts and images in the same vector space with Titan Multimodal Embeddings G1.
+// Each input is embedded into one vector, and can have at most one text part (llms.TextContent)
+// and at most one image part (llms.BinaryContent or llms.ImageURLContent).
+// The embedding model must be of ModelFamilyTitanMultimodalEmbedding (e.g. TitanEmbedImageV1), and the output length can be configured with WithEmbeddingDimensions.
+func (l *LLM) CreateMultimodalEmbedding(ctx context.Context, inputs [][]llms.ContentPart) ([][]float32, error) {
+	l.logger.Debug("bedrock.LLM.CreateMultimodalEmbedding called", "inputs_count", len(inputs))
+	if l.lookupModelFamily(l.embeddingModel) != ModelFamilyTitanMultimodalEmbedding {
+		return nil, newError(ErrUnsupportedModel, "embedding model `%s` does not support multimodal embedding", l.embeddingModel)
+	}
+	if err := l.validateTitanEmbeddingOptions(); err != nil {
+		return nil, err
+	}
+	payloads := make([]*titanEmbeddingRequest, len(inputs))
+	for i, parts := range inputs {
+		payload, err := l.newTitanMultimodalEmbeddingRequest(parts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert input %d: %w", i, err)
+		}
+		payloads[i] = payload
+	}
+	ctx, usage := contextWithInvocationUsage(ctx)
+	resp, err := l.runTitanEmbeddingJobs(ctx, payloads)
+	if err != nil {
+		return nil, err
+	}
+	l.recordEmbeddingUsage(ctx, resp, usage)
+	return resp.Embeddings, nil
+}
+
+func (l *LLM) newTitanMultimodalEmbeddingRequest(parts []llms.ContentPart) (*titanEmbeddingRequest, error) {
+	payload := l.newTitanEmbeddingRequest()
+	for _, part := range parts {
+		switch p := part.(type) {
+		case llms.TextContent:
+			if payload.InputText != "" {
+				return nil, errors.New("multiple text parts are not supported")
+			}
+			payload.InputText = p.Text
+		case llms.ImageURLContent:
+			if payload.InputImage != "" {
+				return nil, errors.New("multiple image parts are not supported")
+			}
+			bs, _, err := fetchImageURL(p.URL)
+			if err != nil {
+				return nil, err
+			}
+			payload.InputImage = base64.StdEncoding.EncodeToString(bs)
+		case llms.BinaryContent:
+			if payload.InputImage != "" {
+				return nil, errors.New("multiple image parts are not supported")
+			}
+			payload.InputImage = base64.StdEncoding.EncodeToString(p.Data)
+		default:
+			return nil, newUnsupportedContentError(part)
+		}
+	}
+	if payload.InputText == "" && payload.InputImage == "" {
+		return nil, errors.New("no text or image part")
+	}
+	return payload, nil
+}
@@ -0,0 +1,84 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// TitanTextRequest is a request body for Amazon Titan Text models.
+// see: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-text.html
+type TitanTextRequest struct {
+	InputText            string                     `json:"inputText"`
+	TextGenerationConfig *TitanTextGenerationConfig `json:"textGenerationConfig,omitempty"`
+}
+
+type TitanTextGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+	Temperature   float64  `json:"temperature,omitempty"`
+	TopP          float64  `json:"topP,omitempty"`
+}
+
+type TitanTextResponse struct {
+	InputTextTokenCount int                       `json:"inputTextTokenCount"`
+	Results             []TitanTextResponseResult `json:"results"`
+}
+
+type TitanTextResponseResult struct {
+	TokenCount       int    `json:"tokenCount"`
+	OutputText       string `json:"outputText"`
+	CompletionReason string `json:"completionReason"`
+}
+
+// renderTitanTextPrompt renders messages into Titan Text conversation format.
+//
+//	{system}\n\nUser: {user}\nBot: {assistant}\nUser: {user}\nBot:
+func renderTitanTextPrompt(messages []llms.MessageContent) (string, error) {
+	return renderChatTranscript(messages, "User:", "Bot:")
+}
+
+func (l *LLM) generateContentWithTitanText(ctx context.Context, messages []llms.MessageContent, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages")
+	}
+	l.applyDefaultCallOptions(opts)
+	prompt, err := renderTitanTextPrompt(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+	payload := TitanTextRequest{
+		InputText: prompt,
+		TextGenerationConfig: &TitanTextGenerationConfig{
+			MaxTokenCount: opts.MaxTokens,
+			StopSequences: opts.StopWords,
+			Temperature:   opts.Temperature,
+			TopP:          opts.TopP,
+		},
+	}
+	l.logger.Debug("generate content with titan text", "payload", payload)
+	var resp TitanTextResponse
+	if err := l.invokeModelWithJSON(ctx, opts.Model, payload, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("no results in response")
+	}
+	choices := make([]*llms.ContentChoice, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		choices = append(choices, &llms.ContentChoice{
+			Content:    r.OutputText,
+			StopReason: r.CompletionReason,
+			GenerationInfo: map[string]interface{}{
+				"model":               opts.Model,
+				"usage.input_tokens":  resp.InputTextTokenCount,
+				"usage.output_tokens": r.TokenCount,
+			},
+		})
+	}
+	return &llms.ContentResponse{
+		Choices: choices,
+	}, nil
+}
@@ -0,0 +1,274 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"strconv"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ModelPrice is the on-demand price of a model in USD per 1,000 tokens.
+type ModelPrice struct {
+	InputPer1KTokens  float64
+	OutputPer1KTokens float64
+}
+
+// PriceTable maps model IDs to their prices.
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable returns approximate on-demand prices in us-east-1.
+// Prices change over time and differ by region, so pass your own table to NewUsageTracker for accurate accounting.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		Claude2:                   {InputPer1KTokens: 0.008, OutputPer1KTokens: 0.024},
+		ClaudeInstant:             {InputPer1KTokens: 0.0008, OutputPer1KTokens: 0.0024},
+		Claude3Sonnet:             {InputPer1KTokens: 0.003, OutputPer1KTokens: 0.015},
+		Claude3Haiku:              {InputPer1KTokens: 0.00025, OutputPer1KTokens: 0.00125},
+		Llama2Chat13B:             {InputPer1KTokens: 0.00075, OutputPer1KTokens: 0.001},
+		Llama2Chat70B:             {InputPer1KTokens: 0.00195, OutputPer1KTokens: 0.00256},
+		Llama3Instruct8B:          {InputPer1KTokens: 0.0003, OutputPer1KTokens: 0.0006},
+		Llama3Instruct70B:         {InputPer1KTokens: 0.00265, OutputPer1KTokens: 0.0035},
+		Mistral7BInstruct:         {InputPer1KTokens: 0.00015, OutputPer1KTokens: 0.0002},
+		Mixtral8x7BInstruct:       {InputPer1KTokens: 0.00045, OutputPer1KTokens: 0.0007},
+		MistralLarge:              {InputPer1KTokens: 0.004, OutputPer1KTokens: 0.012},
+		TitanTextLite:             {InputPer1KTokens: 0.00015, OutputPer1KTokens: 0.0002},
+		TitanTextExpress:          {InputPer1KTokens: 0.0002, OutputPer1KTokens: 0.0006},
+		TitanTextPremier:          {InputPer1KTokens: 0.0005, OutputPer1KTokens: 0.0015},
+		CohereCommand:             {InputPer1KTokens: 0.0015, OutputPer1KTokens: 0.002},
+		CohereCommandLight:        {InputPer1KTokens: 0.0003, OutputPer1KTokens: 0.0006},
+		CohereCommandR:            {InputPer1KTokens: 0.0005, OutputPer1KTokens: 0.0015},
+		CohereCommandRPlus:        {InputPer1KTokens: 0.003, OutputPer1KTokens: 0.015},
+		AI21J2Mid:                 {InputPer1KTokens: 0.0125, OutputPer1KTokens: 0.0125},
+		AI21J2Ultra:               {InputPer1KTokens: 0.0188, OutputPer1KTokens: 0.0188},
+		AI21JambaInstruct:         {InputPer1KTokens: 0.0005, OutputPer1KTokens: 0.0007},
+		TitanEmbeddingG1Text:      {InputPer1KTokens: 0.0001},
+		TitanEmbedTextV2:          {InputPer1KTokens: 0.00002},
+		TitanEmbedImageV1:         {InputPer1KTokens: 0.0008},
+		CohereEmbedEnglishV3:      {InputPer1KTokens: 0.0001},
+		CohereEmbedMultilingualV3: {InputPer1KTokens: 0.0001},
+	}
+}
+
+// ModelUsage is the aggregated usage of a model.
+type ModelUsage struct {
+	// Requests is the number of GenerateContent and CreateEmbedding calls.
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	// CostUSD is the estimated cost by the price table. Models missing in the table cost 0.
+	CostUSD float64
+}
+
+// UsageTracker aggregates token usage and estimated cost per model ID.
+// It is safe for concurrent use.
+type UsageTracker struct {
+	mu     sync.Mutex
+	prices PriceTable
+	usage  map[string]ModelUsage
+}
+
+// NewUsageTracker returns a new UsageTracker with the price table. If prices is nil, DefaultPriceTable is used.
+func NewUsageTracker(prices PriceTable) *UsageTracker {
+	if prices == nil {
+		prices = DefaultPriceTable()
+	}
+	return &UsageTracker{
+		prices: prices,
+		usage:  make(map[string]ModelUsage),
+	}
+}
+
+// Record records a request of the model ID with the tokens.
+// The price is looked up by the model ID, or the foundation model ID for ARNs and inference profiles.
+func (t *UsageTracker) Record(modelID string, inputTokens, outputTokens int) {
+	t.record(modelID, resolveModelID(modelID), inputTokens, outputTokens)
+}
+
+func (t *UsageTracker) record(modelID string, priceModelID string, inputTokens, outputTokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	price, ok := t.prices[modelID]
+	if !ok {
+		price = t.prices[priceModelID]
+	}
+	u := t.usage[modelID]
+	u.Requests++
+	u.InputTokens += inputTokens
+	u.OutputTokens += outputTokens
+	u.CostUSD += float64(inputTokens)/1000*price.InputPer1KTokens + float64(outputTokens)/1000*price.OutputPer1KTokens
+	t.usage[modelID] = u
+}
+
+// Snapshot returns a copy of the aggregated usage per model ID.
+func (t *UsageTracker) Snapshot() map[string]ModelUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.usage)
+}
+
+// TotalCostUSD returns the estimated cost of all models.
+func (t *UsageTracker) TotalCostUSD() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total float64
+	for _, u := range t.usage {
+		total += u.CostUSD
+	}
+	return total
+}
+
+// Reset clears the aggregated usage.
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = make(map[string]ModelUsage)
+}
+
+type usageTrackerKey struct{}
+
+// ContextWithUsageTracker returns a new context with the tracker, that records the usage of the calls with the context
+// in addition to the tracker configured by WithUsageTracker. It can be used to account usage per feature.
+func ContextWithUsageTracker(ctx context.Context, tracker *UsageTracker) context.Context {
+	return context.WithValue(ctx, usageTrackerKey{}, tracker)
+}
+
+// recordUsage records the usage to the trackers of the LLM and the context.
+func (l *LLM) recordUsage(ctx context.Context, modelID string, inputTokens, outputTokens int) {
+	priceModelID := resolveModelID(modelID)
+	if base, ok := l.modelAliases[modelID]; ok {
+		priceModelID = resolveModelID(base)
+	}
+	if l.usageTracker != nil {
+		l.usageTracker.record(modelID, priceModelID, inputTokens, outputTokens)
+	}
+	if tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker); ok && tracker != l.usageTracker {
+		tracker.record(modelID, priceModelID, inputTokens, outputTokens)
+	}
+}
+
+// invocationUsage accumulates the token counts reported by Bedrock for the calls of a GenerateContent or CreateEmbedding,
+// from the X-Amzn-Bedrock-Input-Token-Count and X-Amzn-Bedrock-Output-Token-Count headers of InvokeModel
+// and the invocation metrics of InvokeModelWithResponseStream.
+type invocationUsage struct {
+	mu           sync.Mutex
+	reported     bool
+	inputTokens  int
+	outputTokens int
+}
+
+type invocationUsageKey struct{}
+
+func contextWithInvocationUsage(ctx context.Context) (context.Context, *invocationUsage) {
+	u := &invocationUsage{}
+	return context.WithValue(ctx, invocationUsageKey{}, u), u
+}
+
+// addInvocationUsage adds the token counts to the invocationUsage of the context, if any.
+func addInvocationUsage(ctx context.Context, inputTokens, outputTokens int) {
+	u, ok := ctx.Value(invocationUsageKey{}).(*invocationUsage)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.reported = true
+	u.inputTokens += inputTokens
+	u.outputTokens += outputTokens
+}
+
+// get returns the accumulated token counts, and whether any call reported them.
+func (u *invocationUsage) get() (int, int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.inputTokens, u.outputTokens, u.reported
+}
+
+// tokenCountsFromMetadata returns the token counts in the response headers of InvokeModel.
+func tokenCountsFromMetadata(metadata middleware.Metadata) (int, int, bool) {
+	resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response)
+	if !ok || resp.Response == nil {
+		return 0, 0, false
+	}
+	inputTokens, err := strconv.Atoi(resp.Header.Get("X-Amzn-Bedrock-Input-Token-Count"))
+	if err != nil {
+		return 0, 0, false
+	}
+	outputTokens, _ := strconv.Atoi(resp.Header.Get("X-Amzn-Bedrock-Output-Token-Count"))
+	return inputTokens, outputTokens, true
+}
+
+// invocationMetricsChunk is the last chunk of InvokeModelWithResponseStream, that has the invocation metrics.
+type invocationMetricsChunk struct {
+	Metrics *struct {
+		InputTokenCount  int `json:"inputTokenCount"`
+		OutputTokenCount int `json:"outputTokenCount"`
+	} `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// addInvocationUsageFromChunk adds the token counts of the invocation metrics in the stream chunk, if any.
+func addInvocationUsageFromChunk(ctx context.Context, chunk []byte) {
+	if !bytes.Contains(chunk, []byte(`"amazon-bedrock-invocationMetrics"`)) {
+		return
+	}
+	var c invocationMetricsChunk
+	if err := json.Unmarshal(chunk, &c); err != nil || c.Metrics == nil {
+		return
+	}
+	addInvocationUsage(ctx, c.Metrics.InputTokenCount, c.Metrics.OutputTokenCount)
+}
+
+// recordContentResponseUsage records the usage of the model that answered.
+// The token counts reported by Bedrock are used if any, otherwise the usage in GenerationInfo:
+// the input tokens of the first choice, since the choices share the same prompt, and the sum of the output tokens.
+// Codecs of models that report the usage of all the choices together set it on the first choice only.
+func (l *LLM) recordContentResponseUsage(ctx context.Context, resp *llms.ContentResponse, usage *invocationUsage) {
+	if len(resp.Choices) == 0 {
+		return
+	}
+	modelID, _ := resp.Choices[0].GenerationInfo["model"].(string)
+	if modelID == "" {
+		return
+	}
+	if inputTokens, outputTokens, ok := usage.get(); ok {
+		l.recordUsage(ctx, modelID, inputTokens, outputTokens)
+		return
+	}
+	inputTokens := intFromGenerationInfo(resp.Choices[0].GenerationInfo, "usage.input_tokens")
+	var outputTokens int
+	for _, choice := range resp.Choices {
+		outputTokens += intFromGenerationInfo(choice.GenerationInfo, "usage.output_tokens")
+	}
+	l.recordUsage(ctx, modelID, inputTokens, outputTokens)
+}
+
+// recordEmbeddingUsage records the usage of the embedding model.
+// The input tokens reported by Bedrock are used if any, otherwise the token counts in the response body.
+func (l *LLM) recordEmbeddingUsage(ctx context.Context, resp *EmbeddingResponse, usage *invocationUsage) {
+	inputTokens, _, ok := usage.get()
+	if !ok {
+		inputTokens = resp.TotalInputTokenCount()
+	}
+	l.recordUsage(ctx, l.embeddingModel, inputTokens, 0)
+}
+
+func intFromGenerationInfo(info map[string]interface{}, key string) int {
+	switch v := info[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}